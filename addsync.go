@@ -0,0 +1,128 @@
+package dirwatch
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// AddRecursiveSync adds root to be watched recursively, like Add, but walks
+// and registers the whole tree before returning. It returns the directories
+// that got watched, root first, and the errors of the ones that could not be
+// read or watched, so tooling can see what is actually watched. It reads
+// several directories at once, but registers them in the order of Add.
+func (dw *Watcher) AddRecursiveSync(root string) (watched []string, errs []error) {
+	dw.addTree(root, true, func(dir string, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		watched = append(watched, dir)
+	})
+	return watched, errs
+}
+
+// WatchProgress reports the progress of an AddAsync.
+type WatchProgress struct {
+	// Watched is the number of directories watched so far, the root
+	// included.
+	Watched int
+	// Dir is the directory just watched, or the one that failed.
+	Dir string
+	// Err is the error of watching Dir, if it failed.
+	Err error
+	// Done is set on the last report, once the tree is fully registered.
+	Done bool
+}
+
+// AddAsync adds root to be watched, like Add, and returns a channel
+// reporting the progress of registering it: one report for each directory
+// watched or failed, then a last one with Done set, after which the channel
+// is closed. It is meant for showing progress while a large tree gets
+// watched. The channel must be drained; the registration waits for it.
+func (dw *Watcher) AddAsync(root string, recursive bool) <-chan WatchProgress {
+	progress := make(chan WatchProgress, 100)
+	go func() {
+		defer close(progress)
+		var watched int
+		send := func(p WatchProgress) {
+			select {
+			case progress <- p:
+			case <-dw.stopped():
+			}
+		}
+		dw.addTree(root, recursive, func(dir string, err error) {
+			if err == nil {
+				watched++
+			}
+			send(WatchProgress{Watched: watched, Dir: dir, Err: err})
+		})
+		send(WatchProgress{Watched: watched, Done: true})
+	}()
+	return progress
+}
+
+// addTree adds root to be watched and, if recursive, walks and registers
+// its sub-directories before returning. It calls report, one call at a
+// time, with each directory watched, or with the error of one that failed.
+func (dw *Watcher) addTree(root string, recursive bool, report func(dir string, err error)) {
+	var mx sync.Mutex
+	reportSafe := func(dir string, err error) {
+		mx.Lock()
+		defer mx.Unlock()
+		report(dir, err)
+	}
+
+	v, err := filepath.Abs(root)
+	if err != nil {
+		reportSafe(root, err)
+		return
+	}
+	if err := dw.addRoot(context.Background(), fspath{path: v, recursive: &recursive, noWalk: true}); err != nil {
+		reportSafe(v, err)
+		return
+	}
+	var wp watchedPath
+	var known bool
+	if err := dw.call(func(backend) { wp, known = dw.paths[v] }); err != nil {
+		reportSafe(v, err)
+		return
+	}
+	if !known {
+		reportSafe(v, errors.Errorf("root %s is not watched", v))
+		return
+	}
+	reportSafe(v, nil)
+	if !recursive || !wp.dir {
+		return
+	}
+
+	onErr := func(err error) { reportSafe("", err) }
+	for dirs := range dw.dirTree(dw.ctx, v, walkWorkers, onErr) {
+		var added []string
+		var failed []error
+		err := dw.call(func(watcher backend) {
+			for _, dir := range dirs {
+				if err := dw.onAdd(watcher, fspath{path: dir}); err != nil {
+					failed = append(failed, err)
+					continue
+				}
+				if _, ok := dw.paths[dir]; ok {
+					added = append(added, dir)
+				}
+			}
+		})
+		if err != nil {
+			onErr(err)
+			return
+		}
+		for _, err := range failed {
+			onErr(err)
+		}
+		for _, dir := range added {
+			reportSafe(dir, nil)
+		}
+	}
+}
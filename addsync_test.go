@@ -0,0 +1,96 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddRecursiveSync(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-addsync")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	open := filepath.Join(rootDirectory, "open")
+	locked := filepath.Join(rootDirectory, "locked")
+	require.NoError(os.MkdirAll(filepath.Join(open, "sub"), 0777))
+	require.NoError(os.MkdirAll(filepath.Join(locked, "sub"), 0777))
+
+	fake := newFakeBackend()
+	fake.addErr = func(path string) error {
+		if path == locked {
+			return &os.PathError{Op: "add", Path: path, Err: os.ErrPermission}
+		}
+		return nil
+	}
+	watcher := New(
+		Notify(func(Event) {}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	watched, errs := watcher.AddRecursiveSync(rootDirectory)
+	require.Equal(rootDirectory, watched[0])
+	sort.Strings(watched)
+	require.Equal([]string{
+		rootDirectory,
+		filepath.Join(locked, "sub"),
+		open,
+		filepath.Join(open, "sub"),
+	}, watched)
+	require.Len(errs, 1)
+	require.True(os.IsPermission(errors.Cause(errs[0])))
+
+	// everything reported as watched is registered already
+	for _, dir := range watched {
+		require.True(fake.watched(dir))
+	}
+	require.False(fake.watched(locked))
+}
+
+func TestAddAsync(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-addasync")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 3, 3)
+
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	var reports []WatchProgress
+	for p := range watcher.AddAsync(rootDirectory, true) {
+		require.NoError(p.Err)
+		reports = append(reports, p)
+	}
+	require.Len(reports, 1+3+9+27+1)
+	require.Equal(rootDirectory, reports[0].Dir)
+	for i, p := range reports[:len(reports)-1] {
+		require.Equal(i+1, p.Watched)
+		require.False(p.Done)
+		require.True(fake.watched(p.Dir))
+	}
+	last := reports[len(reports)-1]
+	require.True(last.Done)
+	require.Equal(1+3+9+27, last.Watched)
+
+	flat := filepath.Join(rootDirectory, "d0")
+	reports = nil
+	for p := range watcher.AddAsync(flat, false) {
+		reports = append(reports, p)
+	}
+	require.Equal([]WatchProgress{
+		{Watched: 1, Dir: flat},
+		{Watched: 1, Done: true},
+	}, reports)
+}
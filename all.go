@@ -0,0 +1,36 @@
+//go:build go1.23
+// +build go1.23
+
+package dirwatch
+
+import (
+	"context"
+	"iter"
+)
+
+// allBuffer is the buffer of the subscription behind All.
+const allBuffer = 100
+
+// All returns an iterator over the delivered events, for use with range.
+// It yields events until ctx is done or the watcher stops; breaking out of
+// the loop ends its subscription. Like Subscribe, it misses events when the
+// loop body falls behind by more than its buffer.
+func (dw *Watcher) All(ctx context.Context) iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		events, cancel := dw.Subscribe(allBuffer)
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if !yield(ev) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+//go:build go1.23
+// +build go1.23
+
+package dirwatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-all")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	watcher := New(Notify(func(Event) {}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	go func() {
+		<-time.After(time.Millisecond * 50)
+		for i := 0; i < 5; i++ {
+			ioutil.WriteFile(filepath.Join(rootDirectory, fmt.Sprintf("%d.txt", i)), []byte("DATA"), 0644)
+		}
+	}()
+
+	var names []string
+	for ev := range watcher.All(ctx) {
+		if ev.Op&Create == 0 {
+			continue
+		}
+		names = append(names, ev.Name)
+		if len(names) == 2 {
+			break
+		}
+	}
+	require.NoError(ctx.Err())
+	require.Equal([]string{
+		filepath.Join(rootDirectory, "0.txt"),
+		filepath.Join(rootDirectory, "1.txt"),
+	}, names)
+
+	// breaking out of the loop ends the subscription
+	watcher.subs.mx.Lock()
+	require.Len(watcher.subs.set, 0)
+	watcher.subs.mx.Unlock()
+
+	// the iterator ends when the context is done
+	short, cancelShort := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancelShort()
+	for range watcher.All(short) {
+	}
+	require.Error(short.Err())
+}
@@ -0,0 +1,57 @@
+package dirwatch
+
+import "github.com/fsnotify/fsnotify"
+
+// backend is the source of file system notifications the agent watches
+// paths with.
+type backend interface {
+	Add(path string) error
+	Remove(path string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+// withBackend replaces the function that creates the backend of each run
+// of the agent; used in tests.
+func withBackend(newBackend func() (backend, error)) Option {
+	return func(opt *options) {
+		opt.newBackend = newBackend
+	}
+}
+
+// UseWatcher makes the watcher use w, instead of creating its own
+// *fsnotify.Watcher. The caller owns w: Stop does not close it and paths
+// registered on it stay registered after Stop. While the watcher is running,
+// it consumes w.Events and w.Errors, so they must not be read elsewhere.
+// Paths should be added through the watcher, not directly to w.
+func UseWatcher(w *fsnotify.Watcher) Option {
+	return withBackend(func() (backend, error) {
+		return fsnotifyBackend{watcher: w, shared: true}, nil
+	})
+}
+
+type fsnotifyBackend struct {
+	watcher *fsnotify.Watcher
+	shared  bool
+}
+
+func newFsnotifyBackend() (backend, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return fsnotifyBackend{watcher: watcher}, nil
+}
+
+func (b fsnotifyBackend) Add(path string) error         { return b.watcher.Add(path) }
+func (b fsnotifyBackend) Remove(path string) error      { return b.watcher.Remove(path) }
+func (b fsnotifyBackend) Events() <-chan fsnotify.Event { return b.watcher.Events }
+func (b fsnotifyBackend) Errors() <-chan error          { return b.watcher.Errors }
+
+func (b fsnotifyBackend) Close() error {
+	if b.shared {
+		return nil
+	}
+	return b.watcher.Close()
+}
@@ -0,0 +1,131 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend is an in-memory backend, for tests.
+type fakeBackend struct {
+	mx     sync.Mutex
+	paths  map[string]bool
+	added  map[string]int
+	addErr func(path string) error
+	events chan fsnotify.Event
+	errors chan error
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		paths:  make(map[string]bool),
+		added:  make(map[string]int),
+		events: make(chan fsnotify.Event, 100),
+		errors: make(chan error, 100),
+	}
+}
+
+func (b *fakeBackend) Add(path string) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.addErr != nil {
+		if err := b.addErr(path); err != nil {
+			return err
+		}
+	}
+	b.paths[path] = true
+	b.added[path]++
+	return nil
+}
+
+func (b *fakeBackend) Remove(path string) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	delete(b.paths, path)
+	return nil
+}
+
+func (b *fakeBackend) Events() <-chan fsnotify.Event { return b.events }
+func (b *fakeBackend) Errors() <-chan error          { return b.errors }
+func (b *fakeBackend) Close() error                  { return nil }
+
+func (b *fakeBackend) watched(path string) bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.paths[path]
+}
+
+// addCount returns how many times path was added.
+func (b *fakeBackend) addCount(path string) int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.added[path]
+}
+
+func TestNewWithError(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewWithError()
+	require.Error(err)
+
+	failing := func() (backend, error) { return nil, errors.New("too many open files") }
+	_, err = NewWithError(Notify(func(Event) {}), withBackend(failing))
+	require.Error(err)
+	require.Contains(err.Error(), "too many open files")
+
+	fake := newFakeBackend()
+	watcher, err := NewWithError(
+		Notify(func(Event) {}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	require.NoError(err)
+	defer watcher.Stop()
+
+	dir, err := ioutil.TempDir(os.TempDir(), "dirwatch-backend")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(watcher.AddContext(context.Background(), dir, false))
+	require.True(fake.watched(dir))
+}
+
+func TestUseWatcher(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-use-watcher")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	shared, err := fsnotify.NewWatcher()
+	require.NoError(err)
+	defer shared.Close()
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), UseWatcher(shared))
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+
+	name := filepath.Join(rootDirectory, "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+	select {
+	case ev := <-events:
+		require.Equal(name, ev.Name)
+	case <-time.After(time.Second):
+		require.Fail("event not received")
+	}
+
+	watcher.Stop()
+	<-time.After(time.Millisecond * 50)
+
+	// the shared watcher is still open and usable by its owner
+	other, err := ioutil.TempDir(os.TempDir(), "dirwatch-use-watcher")
+	require.NoError(err)
+	defer os.RemoveAll(other)
+	require.NoError(shared.Add(other))
+}
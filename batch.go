@@ -0,0 +1,55 @@
+package dirwatch
+
+import (
+	"github.com/dc0d/retry"
+)
+
+// NotifyBatch delivers events in batches to fn, instead of one by one to
+// notify, for consumers of very high event rates. A batch holds the events
+// observed while the previous call was running, in order. The slice is
+// reused by the next call, so it, and the events in it, are only valid
+// during the call; fn must copy what it keeps. NotifyBatch can be used
+// instead of Notify.
+func NotifyBatch(fn func(events []Event)) Option {
+	return func(opt *options) {
+		opt.batch = fn
+	}
+}
+
+// deliverBatches delivers the queued events to the NotifyBatch function,
+// until the watcher stops.
+func (dw *Watcher) deliverBatches() {
+	var spare []Event
+	for {
+		select {
+		case <-dw.stopped():
+			return
+		case <-dw.queue.ready:
+		}
+		events := dw.queue.swap(spare)
+		taken := len(events)
+		if dw.readsContent() {
+			kept := events[:0]
+			for _, ev := range events {
+				ev, ok := dw.inspect(ev)
+				if !ok {
+					continue
+				}
+				dw.publish(ev)
+				kept = append(kept, ev)
+			}
+			events = kept
+		}
+		if len(events) > 0 {
+			if dw.latency {
+				now := dw.clock.Now()
+				for _, ev := range events {
+					dw.histogram.observe(now.Sub(ev.Time))
+				}
+			}
+			retry.Try(func() error { dw.batch(events); return nil })
+		}
+		dw.undelivered.done(taken)
+		spare = events
+	}
+}
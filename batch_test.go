@@ -0,0 +1,74 @@
+package dirwatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyBatch(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-batch")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	names := make(chan string, 100)
+	watcher := New(NotifyBatch(func(events []Event) {
+		for _, ev := range events {
+			names <- ev.Name
+		}
+	}))
+	defer watcher.Stop()
+
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, false))
+	for i := 0; i < 10; i++ {
+		require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, fmt.Sprintf("%d.txt", i)), []byte("DATA"), 0666))
+	}
+
+	seen := make(map[string]bool)
+T1:
+	for {
+		select {
+		case name := <-names:
+			seen[name] = true
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Len(seen, 10)
+}
+
+func BenchmarkDeliver(b *testing.B) {
+	ev := Event{Name: filepath.Join(os.TempDir(), "file.txt"), Op: Write}
+
+	run := func(b *testing.B, watcher *Watcher, delivered *int64) {
+		defer watcher.Stop()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			watcher.deliver(ev)
+		}
+		for atomic.LoadInt64(delivered) < int64(b.N) {
+			<-time.After(time.Millisecond)
+		}
+	}
+
+	b.Run("notify", func(b *testing.B) {
+		var delivered int64
+		watcher := New(Notify(func(Event) { atomic.AddInt64(&delivered, 1) }))
+		run(b, watcher, &delivered)
+	})
+	b.Run("batch", func(b *testing.B) {
+		var delivered int64
+		watcher := New(NotifyBatch(func(events []Event) { atomic.AddInt64(&delivered, int64(len(events))) }))
+		run(b, watcher, &delivered)
+	})
+}
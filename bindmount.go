@@ -0,0 +1,104 @@
+package dirwatch
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BindMountSafe makes added roots that are files be watched through their
+// parent directory, re-resolving the file, symlinks included, on every
+// event of that directory. A Write event is delivered for the added path
+// whenever the file it resolves to, or its size or modification time,
+// changes; a Remove or Create when it disappears or shows up again. This
+// survives the file being replaced, which breaks a watch on the file
+// itself, e.g. for config mounted in a container. In particular, it
+// handles the updates of Kubernetes ConfigMap and Secret volumes, where
+// config.yaml is a symlink to ..data/config.yaml and ..data a symlink to a
+// timestamped directory: an update writes a new timestamped directory,
+// points a ..data_tmp symlink to it, renames ..data_tmp over ..data and
+// removes the old directory. The other entries of the parent directory are
+// not reported.
+func BindMountSafe() Option {
+	return func(opt *options) {
+		opt.bindMountSafe = true
+	}
+}
+
+type boundFile struct {
+	dir      string
+	exists   bool
+	resolved string
+	size     int64
+	modTime  time.Time
+}
+
+// resolveBound returns the current state of the bound file at path.
+func resolveBound(path string) boundFile {
+	b := boundFile{dir: filepath.Dir(path)}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return b
+	}
+	inf, err := os.Stat(resolved)
+	if err != nil {
+		return b
+	}
+	b.exists = true
+	b.resolved = resolved
+	b.size = inf.Size()
+	b.modTime = inf.ModTime()
+	return b
+}
+
+// bind watches the file root path through its parent directory. It runs on
+// the agent goroutine.
+func (dw *Watcher) bind(watcher backend, path string) error {
+	if _, ok := dw.bound[path]; ok {
+		return nil
+	}
+	b := resolveBound(path)
+	if err := dw.watchDir(watcher, b.dir); err != nil {
+		return err
+	}
+	dw.bound[path] = b
+	dw.trace(path, "bound, resolved to", b.resolved)
+	return nil
+}
+
+// unbind stops watching the bound files at or under path. It runs on the
+// agent goroutine.
+func (dw *Watcher) unbind(watcher backend, path string) {
+	for p, b := range dw.bound {
+		if isUnder(p, path) {
+			delete(dw.bound, p)
+			dw.unwatchDir(watcher, b.dir)
+		}
+	}
+}
+
+// checkBound re-resolves the bound files affected by an event of name, and
+// delivers the changes found. It runs on the agent goroutine.
+func (dw *Watcher) checkBound(name string) {
+	for p, prev := range dw.bound {
+		if name != prev.dir && filepath.Dir(name) != prev.dir {
+			continue
+		}
+		b := resolveBound(p)
+		if b == prev {
+			continue
+		}
+		dw.bound[p] = b
+		var op Op
+		switch {
+		case !b.exists:
+			op = Remove
+		case !prev.exists:
+			op = Create
+		default:
+			op = Write
+		}
+		dw.trace(p, "bound file changed, resolved to", b.resolved)
+		dw.deliver(dw.attribute(Event{Name: p, Op: op, Time: dw.clock.Now()}))
+	}
+}
@@ -0,0 +1,63 @@
+//go:build !windows
+// +build !windows
+
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindMountSafe(t *testing.T) {
+	require := require.New(t)
+
+	// a ConfigMap volume, as laid out by the kubelet
+	volume, err := ioutil.TempDir(os.TempDir(), "dirwatch-bindmount")
+	require.NoError(err)
+	defer os.RemoveAll(volume)
+	first := filepath.Join(volume, "..2024_01_01_00_00_00.000000001")
+	require.NoError(os.Mkdir(first, 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(first, "config.yaml"), []byte("v: 1"), 0644))
+	require.NoError(os.Symlink(filepath.Base(first), filepath.Join(volume, "..data")))
+	config := filepath.Join(volume, "config.yaml")
+	require.NoError(os.Symlink(filepath.Join("..data", "config.yaml"), config))
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), BindMountSafe())
+	defer watcher.Stop()
+	watcher.Add(config, false)
+	<-time.After(time.Millisecond * 50)
+
+	// the update
+	second := filepath.Join(volume, "..2024_01_02_00_00_00.000000002")
+	require.NoError(os.Mkdir(second, 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(second, "config.yaml"), []byte("v: 2"), 0644))
+	tmp := filepath.Join(volume, "..data_tmp")
+	require.NoError(os.Symlink(filepath.Base(second), tmp))
+	require.NoError(os.Rename(tmp, filepath.Join(volume, "..data")))
+	require.NoError(os.RemoveAll(first))
+
+	var got []Event
+T1:
+	for {
+		select {
+		case ev := <-events:
+			got = append(got, ev)
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Len(got, 1)
+	require.Equal(config, got[0].Name)
+	require.Equal(Write, got[0].Op)
+	require.Equal(config, got[0].Root)
+
+	data, err := ioutil.ReadFile(config)
+	require.NoError(err)
+	require.Equal("v: 2", string(data))
+}
@@ -0,0 +1,58 @@
+package dirwatch
+
+import "sync"
+
+// MaxGoroutines bounds the number of goroutines the watcher runs at once
+// to deliver events and to walk recursive roots, to n. Work that finds the
+// budget exhausted is queued, never blocking the watcher, and picked up by
+// the goroutines as they finish their work. Each walk reads one directory
+// at a time, using two goroutines of its own. The goroutines in use are
+// reported in Stats.
+func MaxGoroutines(n int) Option {
+	return func(opt *options) {
+		opt.maxGoroutines = n
+	}
+}
+
+type budget struct {
+	mx      sync.Mutex
+	max     int
+	inUse   int
+	pending []func()
+}
+
+// spawn runs fn on a goroutine of the budget, or queues it.
+func (b *budget) spawn(fn func()) {
+	b.mx.Lock()
+	if b.max > 0 && b.inUse >= b.max {
+		b.pending = append(b.pending, fn)
+		b.mx.Unlock()
+		return
+	}
+	b.inUse++
+	b.mx.Unlock()
+	go b.run(fn)
+}
+
+// run runs fn, then the queued work, until there is none left.
+func (b *budget) run(fn func()) {
+	for fn != nil {
+		fn()
+		b.mx.Lock()
+		if len(b.pending) == 0 {
+			b.inUse--
+			fn = nil
+		} else {
+			fn = b.pending[0]
+			b.pending[0] = nil
+			b.pending = b.pending[1:]
+		}
+		b.mx.Unlock()
+	}
+}
+
+func (b *budget) used() int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.inUse
+}
@@ -0,0 +1,64 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxGoroutines(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-budget")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 3, 3)
+
+	baseline := runtime.NumGoroutine()
+	var delivered int64
+	watcher := New(
+		Notify(func(Event) {
+			<-time.After(time.Millisecond * 5)
+			atomic.AddInt64(&delivered, 1)
+		}),
+		MaxGoroutines(4))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	const files = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < files; i++ {
+			ioutil.WriteFile(filepath.Join(rootDirectory, fmt.Sprintf("%d.txt", i)), nil, 0644)
+		}
+	}()
+
+	var peak, peakUsed int
+	deadline := time.After(time.Second * 10)
+	for atomic.LoadInt64(&delivered) < files {
+		if n := runtime.NumGoroutine() - baseline; n > peak {
+			peak = n
+		}
+		if n := watcher.Stats().Goroutines; n > peakUsed {
+			peakUsed = n
+		}
+		select {
+		case <-deadline:
+			require.FailNow("events not delivered", atomic.LoadInt64(&delivered))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	<-done
+	require.True(peakUsed <= 4, peakUsed)
+	// the budget, the agent, the writer and the ones of fsnotify
+	require.True(peak <= 4+8, peak)
+}
@@ -0,0 +1,31 @@
+package dirwatch
+
+import "path/filepath"
+
+// CanonicalPaths registers every added path by its canonical path, with
+// its symbolic links resolved, so that a directory reachable through more
+// than one path, like a symbolic link and its target, is watched and
+// reported once, whichever path was used to add it. Events are named after
+// the canonical paths. Remove, IsRecursive and SetRecursive accept any of
+// the paths. The cost is a call to filepath.EvalSymlinks on every add,
+// which stats each element of the path, including the adds of the
+// directories found under recursive roots. A path that can not be resolved
+// yet, like one that does not exist, is registered as it is.
+func CanonicalPaths() Option {
+	return func(opt *options) {
+		opt.canonical = true
+	}
+}
+
+// canonicalPath returns the absolute path p with its symbolic links
+// resolved, with CanonicalPaths, or p itself.
+func (dw *Watcher) canonicalPath(p string) string {
+	if !dw.canonical {
+		return p
+	}
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return p
+	}
+	return filepath.Clean(resolved)
+}
@@ -0,0 +1,68 @@
+//go:build !windows
+// +build !windows
+
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalPaths(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-canonical")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	rootDirectory, err = filepath.EvalSymlinks(rootDirectory)
+	require.NoError(err)
+	real := filepath.Join(rootDirectory, "real")
+	link := filepath.Join(rootDirectory, "link")
+	require.NoError(os.Mkdir(real, 0755))
+	require.NoError(os.Symlink(real, link))
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		CanonicalPaths())
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), link, false))
+	require.NoError(watcher.AddContext(context.Background(), filepath.Join(real, "..", "real"), false))
+
+	var paths []string
+	require.NoError(watcher.call(func(backend) {
+		for p := range watcher.paths {
+			paths = append(paths, p)
+		}
+	}))
+	require.Equal([]string{real}, paths)
+	_, known := watcher.IsRecursive(link)
+	require.True(known)
+
+	fp := filepath.Join(link, "text.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	creates := 0
+	for done := false; !done; {
+		select {
+		case ev := <-events:
+			require.Equal(filepath.Join(real, "text.txt"), ev.Name)
+			require.Equal(real, ev.Root)
+			if ev.Op == Create {
+				creates++
+			}
+		case <-time.After(time.Millisecond * 500):
+			done = true
+		}
+	}
+	require.Equal(1, creates)
+
+	require.NoError(watcher.Remove(link))
+	_, known = watcher.IsRecursive(real)
+	require.False(known)
+}
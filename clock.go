@@ -0,0 +1,46 @@
+package dirwatch
+
+import "time"
+
+// clock is the source of time for the watcher; every timestamp and timer
+// of the time-based features goes through it, so tests can drive them
+// with a fake clock instead of sleeping.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) timer
+	AfterFunc(d time.Duration, fn func()) timer
+}
+
+// timer is the part of *time.Timer the watcher uses.
+type timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// withClock sets the clock of the watcher, for tests.
+func withClock(c clock) Option {
+	return func(opt *options) {
+		opt.clock = c
+	}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTimer(d time.Duration) timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, fn func()) timer {
+	return realTimer{time.AfterFunc(d, fn)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
@@ -0,0 +1,174 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock that only moves on Advance, for tests. Timers fire
+// on the goroutine calling Advance.
+type fakeClock struct {
+	mx        sync.Mutex
+	cond      *sync.Cond
+	now       time.Time
+	timers    []*fakeTimer
+	scheduled int
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.cond = sync.NewCond(&c.mx)
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time { return c.NewTimer(d).C() }
+
+func (c *fakeClock) NewTimer(d time.Duration) timer {
+	return c.newTimer(d, nil)
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, fn func()) timer {
+	return c.newTimer(d, fn)
+}
+
+func (c *fakeClock) newTimer(d time.Duration, fn func()) *fakeTimer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), fn: fn}
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	t.schedule(d)
+	return t
+}
+
+// Advance moves the clock by d, firing the timers that are due, in order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mx.Lock()
+	c.now = c.now.Add(d)
+	var due []*fakeTimer
+	kept := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			kept = append(kept, t)
+			continue
+		}
+		due = append(due, t)
+	}
+	c.timers = kept
+	now := c.now
+	c.mx.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, t := range due {
+		if t.fn != nil {
+			t.fn()
+			continue
+		}
+		select {
+		case t.c <- now:
+		default:
+		}
+	}
+}
+
+// waitTimers blocks until timers have been scheduled n times in total, by
+// creating or resetting them.
+func (c *fakeClock) waitTimers(n int) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for c.scheduled < n {
+		c.cond.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	c     chan time.Time
+	fn    func()
+}
+
+// schedule must be called with the clock locked.
+func (t *fakeTimer) schedule(d time.Duration) {
+	t.at = t.clock.now.Add(d)
+	t.clock.scheduled++
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.cond.Broadcast()
+}
+
+// unschedule must be called with the clock locked.
+func (t *fakeTimer) unschedule() bool {
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mx.Lock()
+	defer t.clock.mx.Unlock()
+	return t.unschedule()
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mx.Lock()
+	defer t.clock.mx.Unlock()
+	active := t.unschedule()
+	t.schedule(d)
+	return active
+}
+
+func TestFakeClockStable(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-clock")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fp := filepath.Join(rootDirectory, "download.bin")
+	require.NoError(ioutil.WriteFile(fp, []byte("CHUNK"), 0644))
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var stable = make(chan Event, 100)
+	watcher := New(
+		Notify(func(Event) {}),
+		OnStable(time.Minute, func(ev Event) { stable <- ev }),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	clock.waitTimers(1)
+	clock.Advance(time.Second * 30)
+	require.Len(stable, 0)
+
+	// a second write pushes the deadline back
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	clock.waitTimers(2)
+	clock.Advance(time.Second * 30)
+	require.Len(stable, 0)
+
+	clock.Advance(time.Second * 30)
+	require.Len(stable, 1)
+	ev := <-stable
+	require.Equal(fp, ev.Name)
+	require.Equal(Write, ev.Op)
+	require.Equal(clock.Now().Add(-time.Minute), ev.Time)
+}
@@ -0,0 +1,112 @@
+package dirwatch
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CoalesceAdds holds the directories found under recursive roots, by their
+// events or by walking, for d before adding them, so that deleting a large
+// tree, like with rm -rf, does not keep the watcher busy adding directories
+// that are on their way out. A directory that is removed or renamed within
+// d, or is under one that is, is never added, and one found more than once
+// within d is added once. The tradeoff is that events in new directories
+// are missed for up to d; ScanExisting does not cover them either.
+func CoalesceAdds(d time.Duration) Option {
+	return func(opt *options) {
+		opt.coalesce = d
+	}
+}
+
+// pendingAdds are the adds held back by CoalesceAdds.
+type pendingAdds struct {
+	mx      sync.Mutex
+	seq     uint64
+	order   []string
+	paths   map[string]pendingAdd
+	removed map[string]uint64
+	armed   bool
+}
+
+type pendingAdd struct {
+	fspath
+	seq uint64
+}
+
+// queueAdds queues the adds of the directories found under recursive
+// roots, holding them back with CoalesceAdds.
+func (dw *Watcher) queueAdds(fsp ...fspath) {
+	if dw.coalesce <= 0 {
+		dw.adds.push(fsp...)
+		return
+	}
+	p := &dw.pending
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	for _, v := range fsp {
+		p.seq++
+		if _, ok := p.paths[v.path]; !ok {
+			p.order = append(p.order, v.path)
+		}
+		p.paths[v.path] = pendingAdd{fspath: v, seq: p.seq}
+	}
+	if p.armed || len(p.paths) == 0 {
+		return
+	}
+	p.armed = true
+	dw.clock.AfterFunc(dw.coalesce, dw.flushAdds)
+}
+
+// dropAdds drops the held back adds of path and the directories under it,
+// queued before it was removed.
+func (dw *Watcher) dropAdds(path string) {
+	if dw.coalesce <= 0 {
+		return
+	}
+	p := &dw.pending
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	if len(p.paths) == 0 {
+		return
+	}
+	p.seq++
+	p.removed[path] = p.seq
+}
+
+// flushAdds queues the held back adds that are still wanted.
+func (dw *Watcher) flushAdds() {
+	p := &dw.pending
+	p.mx.Lock()
+	var batch []fspath
+	for _, path := range p.order {
+		v := p.paths[path]
+		if !p.wasRemoved(v) {
+			batch = append(batch, v.fspath)
+		}
+	}
+	p.order = nil
+	p.paths = make(map[string]pendingAdd)
+	p.removed = make(map[string]uint64)
+	p.armed = false
+	p.mx.Unlock()
+
+	if len(batch) > 0 {
+		dw.adds.push(batch...)
+	}
+}
+
+// wasRemoved reports whether v, or a directory above it, was removed after
+// v was queued. It must be called with p locked.
+func (p *pendingAdds) wasRemoved(v pendingAdd) bool {
+	for dir := v.path; ; {
+		if seq, ok := p.removed[dir]; ok && seq > v.seq {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
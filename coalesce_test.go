@@ -0,0 +1,113 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesceAddsDeletionStorm(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-coalesce")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	big := filepath.Join(rootDirectory, "big")
+	require.NoError(os.Mkdir(big, 0755))
+	makeTree(t, big, 4, 3)
+
+	var dirs []string
+	require.NoError(filepath.Walk(big, func(path string, f os.FileInfo, err error) error {
+		dirs = append(dirs, path)
+		return err
+	}))
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var events = make(chan Event, len(dirs))
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		CoalesceAdds(time.Minute),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+
+	pending := func() int {
+		watcher.pending.mx.Lock()
+		defer watcher.pending.mx.Unlock()
+		return len(watcher.pending.paths)
+	}
+	for pending() < len(dirs) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// rm -rf reports the deepest directories first
+	require.NoError(os.RemoveAll(big))
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		fake.events <- fsnotify.Event{Name: dir, Op: fsnotify.Remove}
+	}
+	for range dirs {
+		select {
+		case <-events:
+		case <-time.After(time.Second * 5):
+			require.FailNow("remove events were not delivered")
+		}
+	}
+
+	clock.Advance(time.Minute)
+	require.NoError(watcher.call(func(backend) {}))
+	time.Sleep(time.Millisecond * 100)
+	require.NoError(watcher.call(func(backend) {}))
+
+	watcher.adds.mu.Lock()
+	pushed := watcher.adds.seq
+	watcher.adds.mu.Unlock()
+	// only the root ever reaches the add queue
+	require.Equal(uint64(1), pushed)
+	for _, dir := range dirs {
+		require.Equal(0, fake.addCount(dir))
+	}
+	require.Equal(0, pending())
+}
+
+func TestCoalesceAdds(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-coalesce")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		CoalesceAdds(time.Minute),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+
+	kept := filepath.Join(rootDirectory, "kept")
+	require.NoError(os.Mkdir(kept, 0755))
+	for i := 0; i < 3; i++ {
+		fake.events <- fsnotify.Event{Name: kept, Op: fsnotify.Create}
+	}
+	clock.waitTimers(1)
+	require.Equal(0, fake.addCount(kept))
+
+	clock.Advance(time.Minute)
+	for fake.addCount(kept) == 0 {
+		time.Sleep(time.Millisecond * 10)
+	}
+	time.Sleep(time.Millisecond * 100)
+	require.Equal(1, fake.addCount(kept))
+}
@@ -0,0 +1,41 @@
+package dirwatch
+
+import "time"
+
+// CollapseSubtreeCreates delivers a single Create event for the top-most
+// newly created directory and suppresses Create events for its descendants
+// that arrive within window (e.g. after mkdir -p a/b/c/d). It is best-effort:
+// descendants created after the window has passed are reported as usual, and
+// so are those whose Create arrives before the one of their ancestor. The
+// suppressed events are dropped before PerFileDebounce sees them, so they
+// never reach the debouncer; the Create of the top-most directory is
+// debounced like any other event.
+func CollapseSubtreeCreates(window time.Duration) Option {
+	return func(opt *options) {
+		opt.collapse = window
+	}
+}
+
+// collapseCreate reports whether ev should be suppressed because one of its
+// ancestor directories was created within the collapse window. It must be
+// called from the agent goroutine.
+func (dw *Watcher) collapseCreate(ev Event, isdir bool) bool {
+	if dw.collapse <= 0 || ev.Op&Create == 0 {
+		return false
+	}
+	now := dw.clock.Now()
+	for p, at := range dw.created {
+		if now.Sub(at) > dw.collapse {
+			delete(dw.created, p)
+		}
+	}
+	for p := range dw.created {
+		if p != ev.Name && isUnder(ev.Name, p) {
+			return true
+		}
+	}
+	if isdir {
+		dw.created[ev.Name] = now
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollapseSubtreeCreates(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-collapse")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var events = make(chan Event, 100)
+	notify := func(ev Event) {
+		events <- ev
+	}
+
+	watcher := New(Notify(notify), CollapseSubtreeCreates(time.Second*5))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 50)
+
+	// create the levels one by one, giving the watcher time to register
+	// each new level, so that without collapsing every level is reported.
+	top := filepath.Join(rootDirectory, "a")
+	level := top
+	for _, name := range []string{"b", "c", "d"} {
+		require.NoError(os.Mkdir(level, 0777))
+		<-time.After(time.Millisecond * 100)
+		level = filepath.Join(level, name)
+	}
+	require.NoError(os.MkdirAll(level, 0777))
+	<-time.After(time.Millisecond * 100)
+
+	var creates []string
+T1:
+	for {
+		select {
+		case ev := <-events:
+			if ev.Op&Create == Create {
+				creates = append(creates, ev.Name)
+			}
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal([]string{top}, creates)
+}
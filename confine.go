@@ -0,0 +1,49 @@
+package dirwatch
+
+import (
+	"path/filepath"
+)
+
+// ConfineTo restricts the watcher to the trees under roots. Events, added
+// paths and discovered directories whose path, with symlinks resolved, is
+// not under one of the roots are dropped and logged. This keeps a symlink
+// from taking the watcher outside of the intended trees.
+func ConfineTo(roots ...string) Option {
+	return func(opt *options) {
+		for _, v := range roots {
+			opt.confine = append(opt.confine, resolvePath(v))
+		}
+	}
+}
+
+const outsideConfinement = "outside of confinement roots"
+
+// confined reports whether path resolves to a path under one of the
+// confinement roots, if any are set.
+func (dw *Watcher) confined(path string) (resolved string, ok bool) {
+	if len(dw.confine) == 0 {
+		return path, true
+	}
+	resolved = resolvePath(path)
+	for _, root := range dw.confine {
+		if isUnder(resolved, root) {
+			return resolved, true
+		}
+	}
+	return resolved, false
+}
+
+// resolvePath returns the absolute path of p with symlinks resolved. If p no
+// longer exists, e.g. for a Remove event, only its parent is resolved.
+func resolvePath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(p)); err == nil {
+		return filepath.Join(parent, filepath.Base(p))
+	}
+	return filepath.Clean(p)
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+// +build !windows
+
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfineTo(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-confine")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	outside, err := ioutil.TempDir(os.TempDir(), "dirwatch-confine-outside")
+	require.NoError(err)
+	defer os.RemoveAll(outside)
+
+	events := make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		ConfineTo(rootDirectory),
+		Logger(func(...interface{}) {}))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	watcher.Add(outside, true)
+	<-time.After(time.Millisecond * 50)
+
+	link := filepath.Join(rootDirectory, "link")
+	require.NoError(os.Symlink(outside, link))
+	<-time.After(time.Millisecond * 100)
+	require.NoError(ioutil.WriteFile(filepath.Join(outside, "text.txt"), []byte("DATA"), 0666))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "text.txt"), []byte("DATA"), 0666))
+
+	var names []string
+T1:
+	for {
+		select {
+		case ev := <-events:
+			names = append(names, ev.Name)
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Contains(names, filepath.Join(rootDirectory, "text.txt"))
+	require.NotContains(names, link)
+	require.NotContains(names, filepath.Join(link, "text.txt"))
+	require.NotContains(names, filepath.Join(outside, "text.txt"))
+
+	ok, reason := watcher.WouldWatch(link)
+	require.False(ok)
+	require.Contains(reason, outsideConfinement)
+}
@@ -0,0 +1,59 @@
+package dirwatch
+
+import (
+	"context"
+	"sync"
+)
+
+// NotifyContext sets a notify callback that gets a context, for callbacks
+// doing blocking I/O. The context is cancelled when the watcher stops, and
+// with CancelSuperseded, when a newer event of the same path supersedes
+// the event.
+func NotifyContext(notify func(ctx context.Context, ev Event)) Option {
+	return func(opt *options) {
+		opt.notifyCtx = notify
+		opt.notify = nil
+		opt.readerFn = nil
+	}
+}
+
+// CancelSuperseded makes the context of a NotifyContext callback to be
+// cancelled when the callback gets called for a newer event of the same
+// path, while it is still running. The newer call is not held back until
+// the older one returns. Since Ordered and NotifyBatch deliver one event at
+// a time, no event is ever superseded with them.
+func CancelSuperseded() Option {
+	return func(opt *options) {
+		opt.supersede = true
+	}
+}
+
+// inflight tracks the contexts of the running NotifyContext callbacks, by
+// path, for CancelSuperseded.
+type inflight struct {
+	mx      sync.Mutex
+	cancels map[string]*context.CancelFunc
+}
+
+// notifyWithContext calls the NotifyContext callback for ev.
+func (dw *Watcher) notifyWithContext(ev Event) error {
+	ctx, cancel := context.WithCancel(dw.ctx)
+	defer cancel()
+	if dw.supersede {
+		dw.inflight.mx.Lock()
+		if older, ok := dw.inflight.cancels[ev.Name]; ok {
+			(*older)()
+		}
+		dw.inflight.cancels[ev.Name] = &cancel
+		dw.inflight.mx.Unlock()
+		defer func() {
+			dw.inflight.mx.Lock()
+			if dw.inflight.cancels[ev.Name] == &cancel {
+				delete(dw.inflight.cancels, ev.Name)
+			}
+			dw.inflight.mx.Unlock()
+		}()
+	}
+	dw.notifyCtx(ctx, ev)
+	return nil
+}
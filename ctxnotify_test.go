@@ -0,0 +1,97 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyContextStop(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-ctxnotify")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var started = make(chan struct{}, 100)
+	var cancelled = make(chan error, 100)
+	watcher := New(NotifyContext(func(ctx context.Context, ev Event) {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+		case <-time.After(time.Second * 30):
+		}
+	}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+
+	time.Sleep(time.Millisecond * 100)
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "slow.txt"), []byte("DATA"), 0644))
+	select {
+	case <-started:
+	case <-time.After(time.Second * 5):
+		require.FailNow("callback was not called")
+	}
+
+	watcher.Stop()
+	select {
+	case err := <-cancelled:
+		require.Equal(context.Canceled, err)
+	case <-time.After(time.Second * 5):
+		require.FailNow("context was not cancelled on stop")
+	}
+}
+
+func TestCancelSuperseded(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-superseded")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fp := filepath.Join(rootDirectory, "data.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+
+	fake := newFakeBackend()
+	var started = make(chan context.Context, 100)
+	watcher := New(
+		NotifyContext(func(ctx context.Context, ev Event) {
+			started <- ctx
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second * 30):
+			}
+		}),
+		CancelSuperseded(),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	next := func() context.Context {
+		select {
+		case ctx := <-started:
+			return ctx
+		case <-time.After(time.Second * 5):
+			require.FailNow("callback was not called")
+		}
+		return nil
+	}
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	first := next()
+	require.NoError(first.Err())
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	second := next()
+	select {
+	case <-first.Done():
+	case <-time.After(time.Second * 5):
+		require.FailNow("superseded context was not cancelled")
+	}
+	require.NoError(second.Err())
+}
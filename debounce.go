@@ -0,0 +1,115 @@
+package dirwatch
+
+import (
+	"sync"
+	"time"
+)
+
+// PerFileDebounce delays the events of each path until it has had no event
+// for d, then delivers its last event only. Each path is debounced on its
+// own, so a file that keeps changing does not hold up the events of the
+// others. A Remove or Rename is delivered right away, dropping the pending
+// event of the path. The pending events are kept in the state bounded by
+// StateCapacity; one whose entry gets evicted is still delivered, and may
+// be delivered along with a later one.
+func PerFileDebounce(d time.Duration) Option {
+	return func(opt *options) {
+		opt.fileDebounce = d
+	}
+}
+
+type debounced struct {
+	mx    sync.Mutex
+	last  Event
+	timer timer
+	done  bool
+}
+
+// pendingDebounces holds the debounced events not delivered yet, whether
+// their state entries got evicted or not, for StopGraceful to flush them.
+type pendingDebounces struct {
+	mx  sync.Mutex
+	set map[*debounced]struct{}
+}
+
+func (p *pendingDebounces) add(d *debounced) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.set[d] = struct{}{}
+}
+
+func (p *pendingDebounces) remove(d *debounced) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	delete(p.set, d)
+}
+
+// take empties p, returning what it held.
+func (p *pendingDebounces) take() []*debounced {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	res := make([]*debounced, 0, len(p.set))
+	for d := range p.set {
+		res = append(res, d)
+	}
+	p.set = make(map[*debounced]struct{})
+	return res
+}
+
+// debounceFile delivers ev, as debounced by PerFileDebounce. It runs on
+// the agent goroutine.
+func (dw *Watcher) debounceFile(ev Event) {
+	if dw.fileDebounce <= 0 || ev.Name == "" {
+		dw.deliver(ev)
+		return
+	}
+	key := stateKey{stateDebounce, ev.Name}
+	if v, ok := dw.state.get(key); ok {
+		d := v.(*debounced)
+		d.mx.Lock()
+		pending := !d.done
+		if pending && ev.Op&(Remove|Rename) == 0 {
+			d.last = ev
+			d.timer.Reset(dw.fileDebounce)
+			d.mx.Unlock()
+			return
+		}
+		if pending {
+			d.done = true
+			d.timer.Stop()
+		}
+		d.mx.Unlock()
+		dw.debouncing.remove(d)
+		dw.state.remove(key)
+	}
+	if ev.Op&(Remove|Rename) != 0 {
+		dw.deliver(ev)
+		return
+	}
+
+	d := &debounced{last: ev}
+	d.mx.Lock()
+	d.timer = dw.clock.AfterFunc(dw.fileDebounce, func() { dw.fireDebounced(key, d) })
+	d.mx.Unlock()
+	dw.debouncing.add(d)
+	dw.state.set(key, d)
+}
+
+// fireDebounced delivers the last event of d, once its path went quiet.
+func (dw *Watcher) fireDebounced(key stateKey, d *debounced) {
+	d.mx.Lock()
+	if d.done {
+		d.mx.Unlock()
+		return
+	}
+	d.done = true
+	ev := d.last
+	d.mx.Unlock()
+	dw.debouncing.remove(d)
+	dw.call(func(backend) {
+		if v, ok := dw.state.get(key); ok && v == d {
+			dw.state.remove(key)
+		}
+		dw.deliver(ev)
+	})
+}
@@ -0,0 +1,82 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerFileDebounce(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-debounce")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	a := filepath.Join(rootDirectory, "a.log")
+	b := filepath.Join(rootDirectory, "b.txt")
+	require.NoError(ioutil.WriteFile(a, []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(b, []byte("DATA"), 0644))
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		PerFileDebounce(time.Millisecond*100),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+
+	next := func() Event {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second * 5):
+			require.FailNow("no event")
+		}
+		return Event{}
+	}
+	none := func() {
+		select {
+		case ev := <-events:
+			require.FailNow("unexpected event", ev.Name)
+		case <-time.After(time.Millisecond * 50):
+		}
+	}
+	write := func(name string, scheduled int) {
+		fake.events <- fsnotify.Event{Name: name, Op: fsnotify.Write}
+		clock.waitTimers(scheduled)
+	}
+
+	write(a, 1)
+	clock.Advance(time.Millisecond * 60)
+	write(a, 2)
+	write(b, 3)
+	clock.Advance(time.Millisecond * 60)
+	write(a, 4)
+
+	// b went quiet, a keeps changing
+	clock.Advance(time.Millisecond * 40)
+	require.Equal(b, next().Name)
+	none()
+
+	clock.Advance(time.Millisecond * 60)
+	ev := next()
+	require.Equal(a, ev.Name)
+	require.Equal(Write, ev.Op)
+	none()
+	require.Equal(0, watcher.Stats().State)
+
+	// a removal cancels the pending event
+	write(a, 5)
+	fake.events <- fsnotify.Event{Name: a, Op: fsnotify.Remove}
+	require.Equal(Remove, next().Op)
+	clock.Advance(time.Millisecond * 100)
+	none()
+	require.Equal(0, watcher.Stats().State)
+}
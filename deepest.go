@@ -0,0 +1,47 @@
+package dirwatch
+
+import "time"
+
+// DeepestPathOnly drops the event of a directory when, within window, an
+// event was reported for a path under it with the same operation, or with
+// any operation if the directory's event is only a Chmod. It reports the
+// most specific path of a change, e.g. a Write of a file without the
+// metadata change of its directory that may come with it. It is a
+// heuristic: an event of a directory that arrives before the events of its
+// contents is not dropped.
+func DeepestPathOnly(window time.Duration) Option {
+	return func(opt *options) {
+		opt.deepest = window
+	}
+}
+
+type recentEvent struct {
+	at time.Time
+	op Op
+}
+
+// shallower reports whether ev is dropped by DeepestPathOnly, and records
+// the events it lets through. It must be called from the agent goroutine.
+func (dw *Watcher) shallower(ev Event, isdir bool) bool {
+	if dw.deepest <= 0 {
+		return false
+	}
+	now := dw.clock.Now()
+	for p, rec := range dw.recent {
+		if now.Sub(rec.at) > dw.deepest {
+			delete(dw.recent, p)
+		}
+	}
+	if isdir {
+		for p, rec := range dw.recent {
+			if p == ev.Name || !isUnder(p, ev.Name) {
+				continue
+			}
+			if ev.Op == Chmod || ev.Op&rec.op == ev.Op {
+				return true
+			}
+		}
+	}
+	dw.recent[ev.Name] = recentEvent{at: now, op: ev.Op}
+	return false
+}
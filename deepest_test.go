@@ -0,0 +1,61 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeepestPathOnly(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-deepest")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	sub := filepath.Join(rootDirectory, "sub")
+	require.NoError(os.Mkdir(sub, 0777))
+	name := filepath.Join(sub, "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+
+	// a fake backend, to control which events get reported
+	fake := newFakeBackend()
+	events := make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		OrderedDelivery(),
+		DeepestPathOnly(time.Millisecond*200),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 50)
+
+	fake.events <- fsnotify.Event{Name: name, Op: fsnotify.Write}
+	fake.events <- fsnotify.Event{Name: sub, Op: fsnotify.Chmod}
+	fake.events <- fsnotify.Event{Name: rootDirectory, Op: fsnotify.Write}
+	fake.events <- fsnotify.Event{Name: sub, Op: fsnotify.Remove}
+	<-time.After(time.Millisecond * 300)
+	fake.events <- fsnotify.Event{Name: sub, Op: fsnotify.Chmod}
+
+	var got []Event
+T1:
+	for {
+		select {
+		case ev := <-events:
+			got = append(got, ev)
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Len(got, 3)
+	require.Equal(name, got[0].Name)
+	require.Equal(sub, got[1].Name)
+	require.Equal(Remove, got[1].Op)
+	require.Equal(sub, got[2].Name)
+	require.Equal(Chmod, got[2].Op)
+}
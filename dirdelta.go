@@ -0,0 +1,67 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// OnDirDelta calls fn with the entries added to and removed from a watched
+// directory each time its listing changes, as base names in sorted order.
+// The listing is cached per directory and kept up to date from the events,
+// so a directory is listed only for its first event; that first call
+// reports the whole listing as added, as a baseline. The listings are kept
+// in the state bounded by StateCapacity, and a directory whose listing got
+// evicted gets a new baseline. fn is called on the agent goroutine, so it
+// must not block.
+func OnDirDelta(fn func(dir string, added, removed []string)) Option {
+	return func(opt *options) {
+		opt.dirDelta = fn
+	}
+}
+
+// trackListing updates the cached listing of the directory of name, with
+// inf being the current info of name, if it exists.
+func (dw *Watcher) trackListing(ev Event, name string, inf os.FileInfo) {
+	if dw.dirDelta == nil {
+		return
+	}
+	if wp, ok := dw.paths[name]; ok && wp.dir && ev.Op&(Remove|Rename) != 0 {
+		dw.state.remove(stateKey{stateListing, name})
+	}
+	dir := filepath.Dir(name)
+	if wp, ok := dw.paths[dir]; !ok || !wp.dir {
+		return
+	}
+
+	key := stateKey{stateListing, dir}
+	v, ok := dw.state.get(key)
+	if !ok {
+		list, err := ioutil.ReadDir(dir)
+		if err != nil {
+			dw.fail(err)
+			return
+		}
+		entries := make(map[string]struct{}, len(list))
+		added := make([]string, 0, len(list))
+		for _, v := range list {
+			entries[v.Name()] = struct{}{}
+			added = append(added, v.Name())
+		}
+		dw.state.set(key, entries)
+		dw.dirDelta(dir, added, nil)
+		return
+	}
+
+	entries := v.(map[string]struct{})
+	base := filepath.Base(name)
+	_, listed := entries[base]
+	switch {
+	case inf == nil && listed && ev.Op&(Remove|Rename) != 0:
+		delete(entries, base)
+		dw.dirDelta(dir, nil, []string{base})
+	case inf != nil && !listed:
+		entries[base] = struct{}{}
+		dw.dirDelta(dir, []string{base}, nil)
+	}
+}
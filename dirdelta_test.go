@@ -0,0 +1,76 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnDirDelta(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-dirdelta")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "existing.txt"), nil, 0644))
+
+	type delta struct {
+		dir            string
+		added, removed []string
+	}
+	var deltas = make(chan delta, 100)
+	watcher := New(
+		Notify(func(Event) {}),
+		OnDirDelta(func(dir string, added, removed []string) {
+			deltas <- delta{dir, added, removed}
+		}))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, false))
+
+	collect := func() (added, removed []string) {
+		for {
+			select {
+			case d := <-deltas:
+				require.Equal(rootDirectory, d.dir)
+				added = append(added, d.added...)
+				removed = append(removed, d.removed...)
+			case <-time.After(time.Millisecond * 300):
+				sort.Strings(added)
+				sort.Strings(removed)
+				return
+			}
+		}
+	}
+	create := func(name string) {
+		require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, name), []byte("DATA"), 0644))
+	}
+
+	// the first event reports the whole listing
+	create("a.txt")
+	added, removed := collect()
+	require.Equal([]string{"a.txt", "existing.txt"}, added)
+	require.Empty(removed)
+
+	create("b.txt")
+	added, removed = collect()
+	require.Equal([]string{"b.txt"}, added)
+	require.Empty(removed)
+
+	// writing to a listed file is no delta
+	create("b.txt")
+	added, removed = collect()
+	require.Empty(added)
+	require.Empty(removed)
+
+	require.NoError(os.Remove(filepath.Join(rootDirectory, "existing.txt")))
+	require.NoError(os.Rename(filepath.Join(rootDirectory, "a.txt"), filepath.Join(rootDirectory, "c.txt")))
+	added, removed = collect()
+	require.Equal([]string{"c.txt"}, added)
+	require.Equal([]string{"a.txt", "existing.txt"}, removed)
+}
@@ -0,0 +1,34 @@
+package dirwatch
+
+// DirEventsOnly drops the events of everything but directories, for tools
+// that only follow the directory structure, like mirroring it. New
+// directories are still recursed into. A Remove or Rename is delivered for
+// the directories the watcher knows of: the watched ones, and the ones it
+// has seen an event for.
+func DirEventsOnly() Option {
+	return func(opt *options) {
+		opt.dirsOnly = true
+	}
+}
+
+// notDirEvent reports whether ev is to be dropped by DirEventsOnly. It runs
+// on the agent goroutine.
+func (dw *Watcher) notDirEvent(ev Event, isdir bool) bool {
+	if !dw.dirsOnly {
+		return false
+	}
+	if isdir {
+		dw.knownDirs[ev.Name] = struct{}{}
+		return false
+	}
+	if ev.Op&(Remove|Rename) == 0 {
+		return true
+	}
+	// a removed directory can not be stat'd anymore
+	_, known := dw.knownDirs[ev.Name]
+	delete(dw.knownDirs, ev.Name)
+	if wp, ok := dw.paths[ev.Name]; ok && wp.dir {
+		known = true
+	}
+	return !known
+}
@@ -0,0 +1,58 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirEventsOnly(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-dirsonly")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		DirEventsOnly())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	time.Sleep(time.Millisecond * 100)
+
+	collect := func() map[string]Op {
+		seen := make(map[string]Op)
+		for {
+			select {
+			case ev := <-events:
+				seen[ev.Name] |= ev.Op
+			case <-time.After(time.Millisecond * 300):
+				return seen
+			}
+		}
+	}
+
+	sub := filepath.Join(rootDirectory, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "file.txt"), []byte("DATA"), 0644))
+	seen := collect()
+	require.Equal(map[string]Op{sub: Create}, seen)
+
+	// new directories are still recursed into
+	nested := filepath.Join(sub, "nested")
+	require.NoError(os.Mkdir(nested, 0755))
+	require.NoError(ioutil.WriteFile(filepath.Join(sub, "file.txt"), []byte("DATA"), 0644))
+	seen = collect()
+	require.Equal(map[string]Op{nested: Create}, seen)
+
+	require.NoError(os.Remove(nested))
+	require.NoError(os.Remove(filepath.Join(sub, "file.txt")))
+	seen = collect()
+	require.Equal(Remove, seen[nested]&Remove)
+	require.NotContains(seen, filepath.Join(sub, "file.txt"))
+}
@@ -2,15 +2,19 @@ package dirwatch
 
 import (
 	"context"
-	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dc0d/retry"
 	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
 )
 
 //-----------------------------------------------------------------------------
@@ -18,15 +22,136 @@ import (
 // Event represents a single file system notification.
 type Event struct {
 	Name string
-	Op   fsnotify.Op
+	Op   Op
+	// Time is when the watcher observed the event.
+	Time time.Time
+
+	// Root is the added path this event is attributed to, the longest
+	// one containing Name.
+	Root string
+	// RelName is Name relative to Root. It is only set when the
+	// RelativeEvents option is used.
+	RelName string
+	// Tag is the value Root was added with, using AddWithTag; see
+	// IncludeTags for tags used to filter events.
+	Tag interface{}
+	// Source is the name of the watcher that reported the event, when it
+	// comes from a MultiWatcher.
+	Source string
+
+	// Size and ModTime are the metadata of Name when the event was
+	// delivered. They are only set for Create and Write events, when the
+	// VerifyWithStat option is used.
+	Size    int64
+	ModTime time.Time
+
+	// ContentType is the detected type of the content of Name, for Create
+	// and Write events, when the SniffContentType option is used.
+	ContentType string
+
+	// Seq is the sequence number of the event in the log of PersistQueue,
+	// to pass to Ack.
+	Seq uint64
+
+	// IsSymlink reports whether Name is a symbolic link itself, as told by
+	// os.Lstat, when the ReportSymlinks option is used. Everything else
+	// follows links: a link to a directory is treated as a directory. A
+	// removed link can not be told apart anymore, so it is not flagged.
+	IsSymlink bool
+
+	// Inode is the inode of the file of Name, when the TrackInodes option
+	// is used, or 0 when it is not known.
+	Inode uint64
+
+	// Coalesced is the number of events this one stands for, itself
+	// included, when the MinInterval option is used.
+	Coalesced int
+
+	// Gone reports that the file of a Create or Write event was gone by
+	// the time the watcher read it, when the NotifyWithReader option is
+	// used.
+	Gone bool
 }
 
 //-----------------------------------------------------------------------------
 
 type options struct {
-	notify  func(Event)
-	exclude []string
-	logger  func(args ...interface{})
+	notify   func(Event) error
+	attempts int
+	backoff  time.Duration
+	exclude  []string
+	logger   func(args ...interface{})
+	collapse time.Duration
+	relative bool
+	special  bool
+	form     *norm.Form
+	latency  bool
+
+	createMissing bool
+	selfWrites    bool
+	countEntries  func(path string, count int)
+	newBackend    func() (backend, error)
+	rawEvent      func(fsnotify.Event)
+	confine       []string
+	dirRate       int
+	ordered       bool
+	scanExisting  bool
+	expandRemoves bool
+	watchFilter   func(path string, info os.FileInfo) bool
+	walkTimeout   time.Duration
+	batch         func(events []Event)
+	xattrs        bool
+	newFilesOnly  bool
+	stateCapacity int
+	hashMax       int64
+	deepest       time.Duration
+	heartbeat     time.Duration
+	clock         clock
+	settle        time.Duration
+	maxAge        time.Duration
+	strictRoots   bool
+	verify        bool
+	onExcluded    func(path, pattern string)
+	middleware    []func(Event) (Event, bool)
+	recurseRecent time.Duration
+	stateChange   func(state WatcherState)
+	excludeFn     func(Event) bool
+	bindMountSafe bool
+	maxGoroutines int
+	sniff         bool
+	persistDir    string
+	leafPatterns  []string
+	fileDebounce  time.Duration
+	dirDelta      func(dir string, added, removed []string)
+	notifyCtx     func(ctx context.Context, ev Event)
+	supersede     bool
+	dirsOnly      bool
+	namespace     map[string]string
+	includeTags   []string
+	excludeTags   []string
+	pollInterval  time.Duration
+	grace         time.Duration
+	graceBuffer   bool
+	symlinks      bool
+	singleAgent   bool
+	inodes        bool
+	coalesce      time.Duration
+	minInterval   time.Duration
+	rootExclude   map[string][]string
+	addBurst      int
+	canonical     bool
+	readerFn      func(ev Event, r io.ReadCloser)
+	readerMax     int64
+	pipes         []string
+
+	stableQuiet time.Duration
+	stableFn    func(Event)
+
+	diffQuiet time.Duration
+	diffFn    func(created, modified, deleted []string)
+
+	rootInterval time.Duration
+	rootFn       func(root string, count int)
 }
 
 // Option modifies the options.
@@ -34,15 +159,75 @@ type Option func(*options)
 
 // Notify sets the notify callback.
 func Notify(notify func(Event)) Option {
+	return func(opt *options) {
+		opt.notify = func(ev Event) error {
+			notify(ev)
+			return nil
+		}
+		opt.notifyCtx = nil
+		opt.readerFn = nil
+	}
+}
+
+// NotifyErr sets a notify callback that can fail. Failed calls are retried
+// as configured by NotifyRetry.
+func NotifyErr(notify func(Event) error) Option {
 	return func(opt *options) {
 		opt.notify = notify
+		opt.notifyCtx = nil
+		opt.readerFn = nil
 	}
 }
 
-// Exclude sets patterns to exclude from watch.
+// NotifyRetry makes a failing notify callback to be called up to attempts
+// times for an event, waiting backoff before the first retry and doubling
+// it afterwards. When the attempts are exhausted, the error is reported
+// on the Errors channel.
+func NotifyRetry(attempts int, backoff time.Duration) Option {
+	return func(opt *options) {
+		opt.attempts = attempts
+		opt.backoff = backoff
+	}
+}
+
+// Exclude adds patterns to exclude from watch. Patterns are matched
+// against absolute paths using path.Match, in slash form on every platform,
+// except for the ones that start with **/, which are matched against the
+// base name of a path, at any depth (e.g. **/.git). Relative patterns, like
+// build/*, are matched against the path relative to each root containing
+// it, independently; see ExcludePerRoot for patterns of one root. A
+// malformed pattern makes New panic, and NewWithError fail.
 func Exclude(exclude ...string) Option {
 	return func(opt *options) {
-		opt.exclude = exclude
+		opt.exclude = append(opt.exclude, exclude...)
+	}
+}
+
+// RelativeEvents sets Event.RelName to the path of the event relative to
+// the root it is attributed to.
+func RelativeEvents() Option {
+	return func(opt *options) {
+		opt.relative = true
+	}
+}
+
+// SkipSpecialFiles makes the watcher ignore paths that are neither regular
+// files nor directories, like FIFOs, sockets and devices. They are not
+// watched and their events are not reported.
+func SkipSpecialFiles() Option {
+	return func(opt *options) {
+		opt.special = true
+	}
+}
+
+// NormalizeUnicode normalizes the paths of delivered events to the given
+// Unicode normalization form. On macOS, HFS+ and APFS may report NFD names
+// for files created with NFC names, so event names would not string-equal
+// the paths a program used. The watcher keeps using the names reported by
+// the file system internally.
+func NormalizeUnicode(form norm.Form) Option {
+	return func(opt *options) {
+		opt.form = &form
 	}
 }
 
@@ -53,180 +238,788 @@ func Logger(logger func(args ...interface{})) Option {
 	}
 }
 
+// WatchFilter is consulted for each directory that is about to be watched;
+// if it returns false, the directory is neither watched nor recursed into.
+// Exclude patterns are applied first. fn can be called concurrently, from
+// the agent goroutine and from the goroutines walking recursive adds, so it
+// must be safe for concurrent use.
+func WatchFilter(fn func(path string, info os.FileInfo) bool) Option {
+	return func(opt *options) {
+		opt.watchFilter = fn
+	}
+}
+
+// ExcludeFunc drops the events for which fn returns true, e.g. to ignore
+// Chmod events under a directory while keeping its Write events. fn sees
+// the event as it would be delivered, and is called after the exclude
+// patterns are applied, from the agent goroutine. It only drops events:
+// the paths it rejects are still watched; use WatchFilter for that.
+func ExcludeFunc(fn func(Event) bool) Option {
+	return func(opt *options) {
+		opt.excludeFn = fn
+	}
+}
+
+// Heartbeat delivers an event with the Beat operation, and no Name,
+// every interval, from the agent goroutine. As long as they arrive, the
+// watcher is alive; if the agent gets stuck, they stop. Heartbeats go
+// straight to delivery: they are not subject to exclusion, muting or rate
+// limits. They reach subscribers too.
+func Heartbeat(interval time.Duration) Option {
+	return func(opt *options) {
+		opt.heartbeat = interval
+	}
+}
+
+// WalkTimeout bounds the time spent on walking the tree of each recursive
+// add. When it passes, the directories found so far stay watched, the rest
+// are not, and a timeout error is reported on the errors channel.
+func WalkTimeout(d time.Duration) Option {
+	return func(opt *options) {
+		opt.walkTimeout = d
+	}
+}
+
+// defaultAddBurst is the default of AddBurst.
+const defaultAddBurst = 64
+
+// AddBurst sets how many pending adds the watcher registers at most between
+// two events, 64 by default. Pending adds are always taken before events,
+// so the sub-directories of a busy tree get watched before the events in
+// them are missed; the bound keeps a large walk from holding up the events
+// in turn. Zero or less registers every pending add at once.
+func AddBurst(n int) Option {
+	return func(opt *options) {
+		opt.addBurst = n
+	}
+}
+
+// OnRawEvent calls fn with every event exactly as fsnotify delivered it,
+// before any exclusion or normalization, for diagnostics. It does not
+// replace notify. fn runs on the agent goroutine and must not block.
+func OnRawEvent(fn func(fsnotify.Event)) Option {
+	return func(opt *options) {
+		opt.rawEvent = fn
+	}
+}
+
 //-----------------------------------------------------------------------------
 
 // Watcher watches over a directory and it's sub-directories, recursively.
 type Watcher struct {
-	notify  func(Event)
-	exclude []string
-	logger  func(args ...interface{})
+	options
 
-	paths  map[string]bool
-	add    chan fspath
-	ctx    context.Context
-	cancel context.CancelFunc
+	paths       map[string]watchedPath
+	roots       map[string]watchedRoot
+	created     map[string]time.Time
+	entries     map[string]map[string]struct{}
+	state       *stateStore
+	lifecycle   lifecycle
+	recent      map[string]recentEvent
+	settling    map[string]*settleWindow
+	bound       map[string]boundFile
+	waiters     map[*fileWaiter]struct{}
+	waitDirs    map[string]int
+	knownDirs   map[string]struct{}
+	stable      stableFiles
+	rootChanges rootChanges
+	diff        quietDiff
+	tracing     tracing
+	budget      budget
+	pqueue      *persistQueue
+	leaves      leafDirs
+	inflight    inflight
+	undelivered undelivered
+	draining    bool
+	polls       polledDirs
+	watchSet    watchSetSubs
+	spacing     spacing
+	rootList    rootList
+	pending     pendingAdds
+	graceWindow *graceWindow
+	notifyFn    atomic.Value
+	limits      dirLimits
+	queue       *eventQueue
+	subs        subscribers
+	mutes       mutes
+	self        selfWrites
+	hashing     hashLocks
+	stale       staleWrites
+	debouncing  pendingDebounces
+	counters    *counters
+	histogram   *histogram
+	adds        *addQueue
+	calls       chan func(backend)
+	errs        chan error
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+type watchedPath struct {
+	recursive bool
+	dir       bool
+}
+
+type watchedRoot struct {
+	recursive bool
+	tag       interface{}
 }
 
 type fspath struct {
 	path      string
 	recursive *bool
+	priority  int
+	tag       interface{}
+	noWalk    bool
+	descend   bool
+	ctx       context.Context
+	done      chan error
 }
 
-// New creates a new *Watcher. Excluded patterns are based on
-// filepath.Match function patterns.
+// New creates a new *Watcher. It panics on invalid options, like a
+// malformed Exclude pattern. If the watcher can not be started, it keeps
+// retrying in the background; see NewWithError.
 func New(opt ...Option) *Watcher {
-	o := &options{}
+	o := newOptions(opt...)
+	if err := o.validate(); err != nil {
+		panic(err.Error())
+	}
+	res := newWatcher(o)
+	res.start()
+	return res
+}
+
+// NewWithError creates a new *Watcher, like New, but returns an error
+// instead of panicking on invalid options, or if the watcher fails to
+// start (e.g. when no more file descriptors are available).
+func NewWithError(opt ...Option) (*Watcher, error) {
+	o := newOptions(opt...)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	res := newWatcher(o)
+	if err := res.start(); err != nil {
+		res.Stop()
+		return nil, err
+	}
+	return res, nil
+}
+
+func newOptions(opt ...Option) *options {
+	o := &options{
+		selfWrites:    true,
+		newBackend:    newFsnotifyBackend,
+		stateCapacity: defaultStateCapacity,
+		addBurst:      defaultAddBurst,
+		clock:         realClock{},
+	}
 	for _, v := range opt {
 		v(o)
 	}
-	if o.notify == nil {
-		panic("notify can not be nil")
-	}
 	if o.logger == nil {
 		o.logger = log.Println
 	}
+	return o
+}
+
+// validate returns the first problem with o.
+func (o *options) validate() error {
+	if o.notify == nil && o.notifyCtx == nil && o.readerFn == nil && o.batch == nil {
+		return errors.New("notify can not be nil")
+	}
+	for _, ptrn := range o.exclude {
+		if err := validatePattern(ptrn); err != nil {
+			return err
+		}
+	}
+	for _, ptrn := range o.leafPatterns {
+		if err := validatePattern(ptrn); err != nil {
+			return err
+		}
+	}
+	for _, patterns := range o.rootExclude {
+		for _, ptrn := range patterns {
+			if err := validatePattern(ptrn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
+func newWatcher(o *options) *Watcher {
 	res := &Watcher{
-		add:     make(chan fspath),
-		paths:   make(map[string]bool),
-		notify:  o.notify,
-		exclude: o.exclude,
-		logger:  o.logger,
+		options:     *o,
+		adds:        newAddQueue(),
+		paths:       make(map[string]watchedPath),
+		roots:       make(map[string]watchedRoot),
+		calls:       make(chan func(backend)),
+		errs:        make(chan error, 100),
+		created:     make(map[string]time.Time),
+		entries:     make(map[string]map[string]struct{}),
+		state:       newStateStore(o.stateCapacity),
+		recent:      make(map[string]recentEvent),
+		settling:    make(map[string]*settleWindow),
+		bound:       make(map[string]boundFile),
+		waiters:     make(map[*fileWaiter]struct{}),
+		waitDirs:    make(map[string]int),
+		knownDirs:   make(map[string]struct{}),
+		stable:      stableFiles{timers: make(map[string]timer)},
+		rootChanges: rootChanges{counts: make(map[string]int)},
+		diff:        quietDiff{changes: make(map[string]diffKind)},
+		tracing:     tracing{paths: make(map[string]struct{})},
+		budget:      budget{max: o.maxGoroutines},
+		leaves:      leafDirs{timers: make(map[string]timer)},
+		inflight:    inflight{cancels: make(map[string]*context.CancelFunc)},
+		polls:       polledDirs{listings: make(map[string]map[string]polledEntry)},
+		pending: pendingAdds{
+			paths:   make(map[string]pendingAdd),
+			removed: make(map[string]uint64),
+		},
+		limits: dirLimits{
+			buckets: make(map[string]*tokenBucket),
+			dropped: make(map[string]uint64),
+		},
+		mutes:      mutes{until: make(map[string]time.Time)},
+		self:       selfWrites{pending: make(map[selfWrite]time.Time)},
+		hashing:    hashLocks{paths: make(map[string]*hashLock)},
+		stale:      staleWrites{pending: make(map[string]Event)},
+		debouncing: pendingDebounces{set: make(map[*debounced]struct{})},
+		counters:   &counters{},
+		histogram:  newHistogram(),
+		queue:      newEventQueue(),
+		subs:       subscribers{set: make(map[chan Event]Op)},
+		watchSet:   watchSetSubs{set: make(map[chan WatchSetEvent]struct{})},
 	}
 	res.ctx, res.cancel = context.WithCancel(context.Background())
-
-	res.start()
+	res.startGrace()
+	if res.notifyCtx != nil {
+		res.notify = res.notifyWithContext
+	}
+	if res.readerFn != nil {
+		res.notify = res.notifyWithReader
+	}
+	if res.notify != nil {
+		res.setNotify(res.notify)
+	}
+	if res.rootFn != nil {
+		go res.flushRootChanges()
+	}
+	if res.pollInterval > 0 {
+		go res.pollDirs()
+	}
+	for _, p := range res.pipes {
+		go res.watchPipe(p)
+	}
+	switch {
+	case res.batch != nil:
+		go res.deliverBatches()
+	case res.ordered:
+		go res.deliverOrdered()
+	}
 	return res
 }
 
 // Stop stops the watcher. Safe to be called mutiple times.
 func (dw *Watcher) Stop() {
 	dw.cancel()
+	dw.closeQueue()
+	dw.setState(Stopped)
+	dw.unsubscribeAll()
+	dw.closeWatchSet()
+}
+
+// Errors returns the channel on which the watcher reports errors, which
+// are logged too. It is buffered; if nobody reads it, errors are dropped
+// once it is full.
+func (dw *Watcher) Errors() <-chan error {
+	return dw.errs
 }
 
-// Add adds a path to be watched.
+// Add adds a path to be watched. It does not wait for the path to be
+// registered, use AddContext for that; the path and the sub-directories of a
+// recursive add are registered in the background, in the order of
+// filepath.Walk, each directory before the ones under it, or level by level
+// with StrictSingleAgent. Adding a path that is already covered by a
+// recursive root records it as a root of its own (e.g. for Event.Root),
+// without watching it twice.
 func (dw *Watcher) Add(path string, recursive bool) {
-	started := make(chan struct{})
-	go func() {
-		close(started)
-		v, err := filepath.Abs(path)
-		if err != nil {
-			dw.logger(err)
-			return
+	dw.AddWithPriority(path, recursive, 0)
+}
+
+// AddWithPriority adds a path to be watched. Pending adds with a higher
+// priority get registered first. Directories found by a recursive add
+// inherit the priority of their root.
+func (dw *Watcher) AddWithPriority(path string, recursive bool, priority int) {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		dw.fail(err)
+		return
+	}
+	dw.adds.push(fspath{path: v, recursive: &recursive, priority: priority})
+}
+
+// AddWithTag adds a path to be watched, like Add, and attaches tag to it.
+// Events attributed to this root carry tag in Event.Tag. Adding the path
+// again replaces its tag.
+func (dw *Watcher) AddWithTag(path string, recursive bool, tag interface{}) {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		dw.fail(err)
+		return
+	}
+	dw.adds.push(fspath{path: v, recursive: &recursive, tag: tag})
+}
+
+// AddContext adds a path to be watched, like Add, and returns the error
+// of registering it. If ctx is done before the path gets registered, it
+// gives up and returns ctx.Err().
+func (dw *Watcher) AddContext(ctx context.Context, path string, recursive bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	v, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return dw.addRoot(ctx, fspath{path: v, recursive: &recursive})
+}
+
+// addRoot queues fsp and waits for the agent to process it.
+func (dw *Watcher) addRoot(ctx context.Context, fsp fspath) error {
+	fsp.ctx = ctx
+	fsp.done = make(chan error, 1)
+	dw.adds.push(fsp)
+	select {
+	case err := <-fsp.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-dw.stopped():
+		return ErrWatcherStopped
+	}
+}
+
+// Remove stops watching path, which was added before. Paths that are still
+// covered by another added root stay watched; e.g. after Add("/a", true) and
+// Add("/a/b", true), removing /a/b keeps it watched as part of /a.
+func (dw *Watcher) Remove(path string) error {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	v = dw.canonicalPath(v)
+	return dw.call(func(watcher backend) {
+		dw.onRemove(watcher, v)
+	})
+}
+
+// WouldWatch reports whether path would be watched if it was added and,
+// if not, a human-readable reason. It has no side effects.
+func (dw *Watcher) WouldWatch(path string) (bool, string) {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		return false, err.Error()
+	}
+	reason, err := dw.admit(v)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, "does not exist"
 		}
-		select {
-		case dw.add <- fspath{path: v, recursive: &recursive}:
-		case <-dw.stopped():
-			return
+		return false, err.Error()
+	}
+	return reason == "", reason
+}
+
+// IsRecursive reports whether path is watched recursively, and whether it
+// is watched at all. Paths under a recursive root are reported as
+// recursive too.
+func (dw *Watcher) IsRecursive(path string) (recursive bool, known bool) {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		return false, false
+	}
+	v = dw.canonicalPath(v)
+	err = dw.call(func(backend) {
+		var wp watchedPath
+		wp, known = dw.paths[v]
+		recursive = known && (wp.recursive || dw.coveredByAncestor(v))
+	})
+	if err != nil {
+		return false, false
+	}
+	return recursive, known
+}
+
+// SetRecursive switches root, which was added before, between being
+// watched recursively and not, e.g. to watch it deeply only while it is
+// busy. Going recursive walks root and watches its sub-directories, like
+// Add; going back stops watching the ones not covered by another root,
+// like Remove. Setting the mode root already has does nothing.
+func (dw *Watcher) SetRecursive(root string, recursive bool) error {
+	v, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	v = dw.canonicalPath(v)
+	var (
+		r     watchedRoot
+		added bool
+	)
+	err = dw.call(func(watcher backend) {
+		r, added = dw.roots[v]
+		if added && r.recursive && !recursive {
+			dw.roots[v] = watchedRoot{recursive: false, tag: r.tag}
+			dw.unwatchUncovered(watcher, v)
 		}
-	}()
-	<-started
+	})
+	if err != nil {
+		return err
+	}
+	if !added {
+		return errors.Errorf("%s was not added", v)
+	}
+	if r.recursive || !recursive {
+		return nil
+	}
+	return dw.addRoot(context.Background(), fspath{path: v, recursive: &recursive, tag: r.tag})
 }
 
 //-----------------------------------------------------------------------------
 
 func (dw *Watcher) stopped() <-chan struct{} { return dw.ctx.Done() }
 
-func (dw *Watcher) start() {
-	started := make(chan struct{})
-	go func() {
-		close(started)
-		retry.Retry(
-			dw.agent,
-			-1,
-			func(err error) {
-				e := err.(interface{ CausedBy() interface{} })
-				fmt.Printf(">>> %+v\n", e.CausedBy())
-			},
-			time.Second)
-	}()
-	<-started
-	// HACK:
-	<-time.After(time.Millisecond * 500)
-}
-
-func (dw *Watcher) agent() error {
-	watcher, err := fsnotify.NewWatcher()
+// call runs fn on the agent goroutine, which owns the internal state,
+// and waits for it to return.
+func (dw *Watcher) call(fn func(watcher backend)) error {
+	done := make(chan struct{})
+	select {
+	case dw.calls <- func(watcher backend) {
+		defer close(done)
+		fn(watcher)
+	}:
+	case <-dw.stopped():
+		return ErrWatcherStopped
+	}
+	<-done
+	return nil
+}
+
+// startTimeout bounds how long starting the agent can take.
+const startTimeout = time.Second * 10
+
+// start runs the agent, restarting it when it fails, and returns the
+// result of its first start.
+func (dw *Watcher) start() error {
+	first := make(chan error, 1)
+	var once sync.Once
+	started := func(err error) {
+		once.Do(func() { first <- err })
+	}
+	dw.setState(Starting)
+	if err := dw.openQueue(); err != nil {
+		dw.fail(err)
+		return err
+	}
+	go retry.Retry(
+		func() error { return dw.agent(started) },
+		-1,
+		func(err error) {
+			started(err)
+			dw.setState(Degraded)
+			dw.fail(err)
+		},
+		time.Second)
+
+	select {
+	case err := <-first:
+		if err == nil {
+			dw.replayQueue()
+		}
+		return err
+	case <-time.After(startTimeout):
+		return errors.New("timed out starting the watcher")
+	}
+}
+
+func (dw *Watcher) agent(started func(error)) error {
+	select {
+	case <-dw.stopped():
+		return nil
+	default:
+	}
+	if dw.currentState() == Degraded {
+		dw.setState(Recovering)
+	}
+	watcher, err := dw.newBackend()
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	defer watcher.Close()
+	started(nil)
+	dw.setState(Running)
+
+	var (
+		beat      timer
+		heartbeat <-chan time.Time
+	)
+	if dw.heartbeat > 0 {
+		beat = dw.clock.NewTimer(dw.heartbeat)
+		defer beat.Stop()
+		heartbeat = beat.C()
+	}
 
 	for {
+		// stopping and pending adds come first, so that new directories get
+		// watched promptly even when events keep coming
+		select {
+		case <-dw.stopped():
+			return nil
+		case <-dw.adds.ready:
+			dw.takeAdds(watcher)
+		default:
+		}
+
 		select {
 		case <-dw.stopped():
 			return nil
-		case ev := <-watcher.Events:
-			dw.onEvent(Event(ev))
-		case err := <-watcher.Errors:
-			dw.logger(fmt.Sprintf("error: %+v\n", errors.WithStack(err)))
-		case d := <-dw.add:
-			dw.onAdd(watcher, d)
+		case at := <-heartbeat:
+			beat.Reset(dw.heartbeat)
+			if !dw.draining {
+				dw.deliver(Event{Op: Beat, Time: at})
+			}
+		case ev, ok := <-watcher.Events():
+			if !ok {
+				return errors.New("backend events closed")
+			}
+			if dw.draining {
+				continue
+			}
+			if dw.rawEvent != nil {
+				dw.rawEvent(ev)
+			}
+			dw.onEvent(Event{Name: ev.Name, Op: OpFromFsnotify(ev.Op), Time: dw.clock.Now()})
+		case err, ok := <-watcher.Errors():
+			if !ok {
+				return errors.New("backend errors closed")
+			}
+			dw.fail(errors.WithStack(err))
+		case <-dw.adds.ready:
+			dw.takeAdds(watcher)
+		case fn := <-dw.calls:
+			fn(watcher)
 		}
 	}
 }
 
+// takeAdds registers up to AddBurst pending adds, leaving the rest for
+// the next turn of the agent, so that a large walk does not hold up the
+// events. It runs on the agent goroutine.
+func (dw *Watcher) takeAdds(watcher backend) {
+	for n := 0; dw.addBurst <= 0 || n < dw.addBurst; n++ {
+		d, ok := dw.adds.pop()
+		if !ok {
+			return
+		}
+		err := dw.onAdd(watcher, d)
+		if err != nil {
+			dw.fail(err)
+		}
+		if d.done != nil {
+			d.done <- err
+		}
+	}
+	if dw.adds.len() > 0 {
+		dw.adds.signal()
+	}
+}
+
 func (dw *Watcher) onAdd(
-	watcher *fsnotify.Watcher,
-	fsp fspath) {
+	watcher backend,
+	fsp fspath) error {
 	if fsp.path == "" {
-		return
+		return nil
+	}
+	if fsp.ctx != nil && fsp.ctx.Err() != nil {
+		return nil
 	}
 	var err error
 	fsp.path, err = filepath.Abs(fsp.path)
 	if err != nil {
-		dw.logger(err)
-		return
+		return err
 	}
-	_, err = os.Stat(fsp.path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			delete(dw.paths, fsp.path)
-			return
+	if fsp.recursive != nil && dw.createMissing {
+		if err := dw.makeMissing(fsp.path); err != nil {
+			return err
 		}
-		dw.logger(err)
-		return
 	}
-	_, ok := dw.paths[fsp.path]
-	if ok {
-		return
+	fsp.path = dw.canonicalPath(fsp.path)
+	if fsp.recursive != nil && dw.strictRoots {
+		if err := dw.strictRoot(fsp.path); err != nil {
+			return err
+		}
 	}
-	if dw.excludePath(fsp.path) {
-		return
+	reason, err := dw.admit(fsp.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			dw.trace(fsp.path, "skipped: does not exist")
+			dw.unregisterPath(fsp.path)
+			return nil
+		}
+		return tooLong(classify(err), fsp.path)
 	}
-	if err := watcher.Add(fsp.path); err != nil {
-		dw.logger(fmt.Sprintf("on add error: %+v\n", errors.WithStack(err)))
+	if reason != "" {
+		dw.trace(fsp.path, "skipped:", reason)
+		if strings.HasPrefix(reason, outsideConfinement) {
+			dw.logger(fsp.path, reason)
+		}
+		if strings.HasPrefix(reason, excludedByPattern) && dw.onExcluded != nil {
+			dw.onExcluded(fsp.path, strings.TrimPrefix(reason, excludedByPattern))
+		}
+		return nil
 	}
-	recursive, _ := dw.paths[fsp.path]
+	var recursive bool
 	if fsp.recursive != nil {
 		recursive = *fsp.recursive
+		_, known := dw.roots[fsp.path]
+		if !known {
+			dw.startSettle(fsp.path)
+		}
+		dw.roots[fsp.path] = watchedRoot{recursive: recursive, tag: fsp.tag}
+		if !known {
+			dw.listRoots()
+		}
+		if dw.bindMountSafe {
+			if isd, _ := isDir(fsp.path); !isd {
+				return dw.bind(watcher, fsp.path)
+			}
+		}
+	}
+	wp, ok := dw.paths[fsp.path]
+	if ok && (!recursive || wp.recursive) {
+		return nil
+	}
+	if !ok {
+		err := watcher.Add(fsp.path)
+		if polled := dw.pollOverLimit(fsp.path, err); err != nil && !polled {
+			return errors.Wrap(tooLong(classify(err), fsp.path), "on add")
+		}
+		wp.dir, _ = isDir(fsp.path)
+	}
+	wp.recursive = recursive
+	dw.registerPath(fsp.path, wp)
+	dw.trace(fsp.path, "watched, recursive:", recursive)
+	if fsp.recursive != nil && dw.scanExisting && wp.dir {
+		dw.scan(fsp.path, recursive)
+	}
+	if fsp.descend && wp.dir {
+		dw.descend(fsp)
+	}
+	// a recursive ancestor root already takes care of the sub-directories
+	if recursive && wp.dir && !fsp.noWalk && !dw.coveredByAncestor(fsp.path) {
+		if dw.singleAgent {
+			dw.descend(fsp)
+		} else {
+			dw.budget.spawn(func() { dw.walkRoot(fsp) })
+		}
+	}
+	return nil
+}
+
+// walkRoot queues the sub-directories of the recursive root fsp, until the
+// watcher stops or WalkTimeout passes.
+func (dw *Watcher) walkRoot(fsp fspath) {
+	ctx := dw.ctx
+	if dw.walkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dw.walkTimeout)
+		defer cancel()
 	}
-	dw.paths[fsp.path] = recursive
-	isd, _ := isDir(fsp.path)
-	if recursive && isd {
-		go func() {
-			tree := dw.dirTree(fsp.path)
-			for v := range tree {
-				dw.add <- fspath{path: v}
+	tree := dw.dirTree(ctx, fsp.path, 1, dw.fail)
+	for dirs := range tree {
+		batch := make([]fspath, len(dirs))
+		for i, v := range dirs {
+			batch[i] = fspath{path: v, priority: fsp.priority}
+		}
+		dw.queueAdds(batch...)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		dw.fail(errors.Errorf("walking %s timed out after %v", fsp.path, dw.walkTimeout))
+	}
+}
+
+func (dw *Watcher) onRemove(watcher backend, path string) {
+	delete(dw.roots, path)
+	dw.listRoots()
+	dw.unbind(watcher, path)
+	dw.unwatchUncovered(watcher, path)
+}
+
+// unwatchUncovered stops watching path and the paths under it that are not
+// covered by an added root anymore.
+func (dw *Watcher) unwatchUncovered(watcher backend, path string) {
+	for p, wp := range dw.paths {
+		if !isUnder(p, path) {
+			continue
+		}
+		if dw.covered(p) {
+			if p == path {
+				wp.recursive = false
+				dw.paths[p] = wp
 			}
-		}()
+			continue
+		}
+		watcher.Remove(p)
+		dw.unregisterPath(p)
 	}
 }
 
+// covered reports whether p is an added root or lies under a recursive one.
+func (dw *Watcher) covered(p string) bool {
+	if _, ok := dw.roots[p]; ok {
+		return true
+	}
+	return dw.coveredByAncestor(p)
+}
+
+// coveredByAncestor reports whether p lies under a recursive root other
+// than p itself.
+func (dw *Watcher) coveredByAncestor(p string) bool {
+	for root, r := range dw.roots {
+		if r.recursive && root != p && isUnder(p, root) {
+			return true
+		}
+	}
+	return false
+}
+
 func (dw *Watcher) onEvent(ev Event) {
-	if dw.excludePath(ev.Name) {
+	dw.trace(ev.Name, "received", ev.Op)
+	dw.checkBound(ev.Name)
+	if dw.wakeWaiters(ev.Name) {
+		return
+	}
+	ev, inf, err, ok := dw.examine(ev)
+	if !ok {
 		return
 	}
-	// callback
-	go retry.Try(func() error { dw.notify(ev); return nil })
-
 	name := ev.Name
-	isdir, err := isDir(name)
+	isdir := inf != nil && inf.IsDir()
+	dw.filterEvent(ev, inf, isdir)
+	dw.expandRemove(ev, name, inf)
+	dw.countEntry(ev, name, inf)
+	dw.trackListing(ev, name, inf)
+	if wp, ok := dw.paths[name]; ok && inf != nil && wp.dir != isdir {
+		dw.retype(name, wp)
+	}
+	if ev.Op&(Remove|Rename) != 0 {
+		dw.dropAdds(name)
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
-			delete(dw.paths, name)
+			dw.unregisterPath(name)
 		} else {
-			dw.logger(err)
+			dw.fail(tooLong(classify(err), name))
 		}
 		return
 	}
@@ -234,49 +1027,296 @@ func (dw *Watcher) onEvent(ev Event) {
 	if !isdir {
 		return
 	}
+	// only new directories under a recursive root need watching; anything
+	// else would be dropped by onAdd anyway, after a trip through the queue
+	if _, ok := dw.paths[name]; ok || !dw.coveredByAncestor(name) {
+		return
+	}
+	if _, ok := dw.leafOf(name); ok {
+		return
+	}
+
+	dw.queueAdds(fspath{path: name})
+}
+
+// examine drops ev if it is excluded or outside of confinement, and stats
+// its file otherwise, flagging ev as set by the options. It runs on the
+// agent goroutine.
+func (dw *Watcher) examine(ev Event) (Event, os.FileInfo, error, bool) {
+	if dw.excludePath(ev.Name) {
+		return ev, nil, nil, false
+	}
+	if resolved, ok := dw.confined(ev.Name); !ok {
+		dw.logger(ev.Name, outsideConfinement+":", resolved)
+		return ev, nil, nil, false
+	}
+
+	inf, err := os.Stat(ev.Name)
+	if dw.holdStale(ev, inf) {
+		return ev, nil, nil, false
+	}
+	inf, err = dw.verifyStat(ev, inf, err)
+	if inf != nil && dw.isSpecial(inf) {
+		dw.trace(ev.Name, "skipped: special file")
+		return ev, nil, nil, false
+	}
+	return dw.markInode(dw.markSymlink(dw.attribute(ev)), inf), inf, err, true
+}
+
+// filterEvent runs ev through the filters, and delivers it if none of them
+// drops it. Events held back for later are neither counted nor traced until
+// they are passed on. It runs on the agent goroutine.
+func (dw *Watcher) filterEvent(ev Event, inf os.FileInfo, isdir bool) {
+	name := ev.Name
+	filtered := true
+	switch {
+	case dw.unverified(&ev, inf):
+	case dw.isSelfWrite(ev):
+	case dw.isMuted(name):
+		atomic.AddUint64(&dw.counters.muted, 1)
+	case dw.excludeFn != nil && dw.excludeFn(ev):
+	case dw.excludedByTag(ev):
+	case dw.tooOld(inf):
+	case dw.throttled(ev):
+	case dw.notDirEvent(ev, isdir):
+	case dw.notNewFile(ev, isdir):
+	case dw.collapseCreate(ev, isdir):
+	case dw.shallower(ev, isdir):
+	case dw.foldIntoLeaf(ev):
+	case dw.dropGrace():
+	case dw.holdGrace(ev, isdir), dw.holdSettling(ev, isdir):
+		return
+	default:
+		filtered = false
+		dw.pass(ev, isdir)
+	}
+	if filtered {
+		atomic.AddUint64(&dw.counters.filtered, 1)
+		dw.trace(name, "filtered", ev.Op)
+	} else {
+		dw.trace(name, "delivered", ev.Op)
+	}
+}
+
+// pass tracks ev, which made it through filterEvent, for the options that
+// follow files, and delivers it, debounced. It runs on the agent goroutine.
+func (dw *Watcher) pass(ev Event, isdir bool) {
+	dw.trackStable(ev)
+	dw.trackDiff(ev)
+	dw.countRootChange(ev)
+	dw.debounceFile(ev)
+	dw.trackXattrs(ev, isdir)
+}
+
+// heldEvent is an event held back by filterEvent, to be passed on later.
+type heldEvent struct {
+	ev    Event
+	isdir bool
+}
+
+// anyDepth prefixes exclude patterns that match base names at any depth.
+const anyDepth = "**/"
+
+// deliver passes ev, in the NamespaceMap namespace, through the middleware,
+// MinInterval and PersistQueue, then sends it.
+// It runs on the agent goroutine.
+func (dw *Watcher) deliver(ev Event) {
+	ev, ok := dw.runMiddleware(dw.toLogical(ev))
+	if !ok {
+		return
+	}
+	if ev, ok = dw.spaceOut(ev); !ok {
+		return
+	}
+	dw.send(dw.persist(ev))
+}
 
-	go func() {
+// send calls the notify callback for ev on its own goroutine, or right away
+// with StrictSingleAgent, retrying failed calls as configured by
+// NotifyRetry, or queues it for the ordered and batched deliveries.
+func (dw *Watcher) send(ev Event) {
+	if !dw.readsContent() {
+		// without reading to wait for, subscribers get the events in order
+		dw.publish(ev)
+		// otherwise they are counted once inspected
+		atomic.AddUint64(&dw.counters.delivered, 1)
+	}
+	dw.undelivered.add()
+	if dw.ordered || dw.batch != nil {
+		dw.queue.push(ev)
+		return
+	}
+	if dw.singleAgent {
+		dw.dispatch(ev)
+		dw.undelivered.done(1)
+		return
+	}
+	dw.budget.spawn(func() {
+		defer dw.undelivered.done(1)
+		dw.dispatch(ev)
+	})
+}
+
+// dispatch hands ev to notify, and to the subscribers if the content of
+// files is read, unless ContentHashGate drops it. It runs off the agent
+// goroutine, unless StrictSingleAgent is used.
+func (dw *Watcher) dispatch(ev Event) {
+	if dw.readsContent() {
+		var ok bool
+		if ev, ok = dw.inspect(ev); !ok {
+			return
+		}
+		dw.publish(ev)
+	}
+	dw.notifyEvent(ev)
+}
+
+// readsContent reports whether events need the content of their files, as
+// read by ContentHashGate and SniffContentType, before they are delivered.
+func (dw *Watcher) readsContent() bool {
+	return dw.hashMax > 0 || dw.sniff
+}
+
+// inspect applies ContentHashGate and SniffContentType to ev, and reports
+// whether it is to be delivered. It runs off the agent goroutine.
+func (dw *Watcher) inspect(ev Event) (Event, bool) {
+	if dw.sameContent(ev) {
+		atomic.AddUint64(&dw.counters.filtered, 1)
+		return ev, false
+	}
+	atomic.AddUint64(&dw.counters.delivered, 1)
+	return dw.sniffContent(ev), true
+}
+
+// notifyEvent calls notify with ev, retrying it as set by NotifyRetry.
+func (dw *Watcher) notifyEvent(ev Event) {
+	delay := dw.backoff
+	if dw.latency {
+		dw.histogram.observe(dw.clock.Now().Sub(ev.Time))
+	}
+	notify := dw.currentNotify()
+	for attempt := 1; ; attempt++ {
+		err := retry.Try(func() error { return notify(ev) })
+		if err == nil {
+			return
+		}
+		if attempt >= dw.attempts {
+			dw.fail(errors.Wrapf(err, "notify %s", ev.Name))
+			return
+		}
 		select {
+		case <-dw.clock.After(delay):
 		case <-dw.stopped():
 			return
-		case dw.add <- fspath{path: name}:
 		}
-	}()
+		delay *= 2
+	}
 }
 
-func (dw *Watcher) excludePath(p string) bool {
-	for _, ptrn := range dw.exclude {
-		matched, err := filepath.Match(ptrn, p)
-		if err != nil {
-			dw.logger(err)
+// fail logs err and reports it on the errors channel, without blocking.
+func (dw *Watcher) fail(err error) {
+	atomic.AddUint64(&dw.counters.errors, 1)
+	dw.logger(err)
+	select {
+	case dw.errs <- err:
+	default:
+	}
+}
+
+// attribute sets the root of ev, along with the fields that derive from
+// it, and normalizes it.
+func (dw *Watcher) attribute(ev Event) Event {
+	if root, ok := dw.rootOf(ev.Name); ok {
+		ev.Root = root
+		ev.Tag = dw.roots[root].tag
+		if dw.relative {
+			ev.RelName, _ = filepath.Rel(root, ev.Name)
+		}
+	}
+	return dw.normalize(ev)
+}
+
+// normalize applies the NormalizeUnicode form to the paths of ev.
+func (dw *Watcher) normalize(ev Event) Event {
+	if dw.form == nil {
+		return ev
+	}
+	ev.Name = dw.form.String(ev.Name)
+	ev.Root = dw.form.String(ev.Root)
+	ev.RelName = dw.form.String(ev.RelName)
+	return ev
+}
+
+// rootOf finds the longest added root that is p or one of its ancestors.
+func (dw *Watcher) rootOf(p string) (string, bool) {
+	var found string
+	for root := range dw.roots {
+		if len(root) <= len(found) {
 			continue
 		}
-		if matched {
-			return true
+		if isUnder(p, root) {
+			found = root
 		}
 	}
-	return false
+	return found, found != ""
 }
 
-func (dw *Watcher) dirTree(queryRoot string) <-chan string {
-	found := make(chan string)
-	go func() {
-		defer close(found)
-		err := filepath.Walk(queryRoot, func(path string, f os.FileInfo, err error) error {
-			if !f.IsDir() {
-				return nil
-			}
-			if filepath.Clean(path) == filepath.Clean(queryRoot) {
-				return nil
-			}
-			found <- path
-			return nil
-		})
-		if err != nil {
-			dw.logger(fmt.Sprintf("%+v", errors.WithStack(err)))
+// admit applies the checks that decide whether an absolute path gets
+// watched. A non-empty reason explains why the path is skipped. It is shared
+// by onAdd and WouldWatch so the two can not drift apart.
+func (dw *Watcher) admit(path string) (reason string, err error) {
+	inf, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if dw.isSpecial(inf) {
+		return "special file " + inf.Mode().String(), nil
+	}
+	if ptrn, ok := dw.matchExclude(path); ok {
+		return excludedByPattern + ptrn, nil
+	}
+	if resolved, ok := dw.confined(path); !ok {
+		return outsideConfinement + ": " + resolved, nil
+	}
+	if inf.IsDir() && dw.watchFilter != nil && !dw.watchFilter(path, inf) {
+		return "rejected by watch filter", nil
+	}
+	return "", nil
+}
+
+// isSpecial reports whether inf is to be skipped by SkipSpecialFiles.
+func (dw *Watcher) isSpecial(inf os.FileInfo) bool {
+	return dw.special && !inf.IsDir() && !inf.Mode().IsRegular()
+}
+
+func (dw *Watcher) excludePath(p string) bool {
+	ptrn, ok := dw.matchExclude(p)
+	if ok {
+		dw.trace(p, "excluded by pattern", ptrn)
+		if dw.onExcluded != nil {
+			dw.onExcluded(p, ptrn)
 		}
-	}()
-	return found
+	}
+	return ok
+}
+
+func (dw *Watcher) matchExclude(p string) (string, bool) {
+	// the patterns are validated on construction
+	if ptrn, ok, _ := firstMatch(dw.exclude, p); ok {
+		return ptrn, true
+	}
+	return dw.matchRelative(p)
+}
+
+// isUnder reports whether p is dir or one of its descendants.
+func isUnder(p, dir string) bool {
+	if p == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(p, dir)
 }
 
 func isDir(path string) (ok bool, err error) {
@@ -1,16 +1,19 @@
 package dirwatch
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
 )
 
 func TestNew(t *testing.T) {
@@ -47,7 +50,7 @@ func TestNew(t *testing.T) {
 	select {
 	case ev := <-events:
 		if strings.Contains(ev.Name, "dirwatch-example") &&
-			strings.Contains(ev.Name, "lab2") && ev.Op == fsnotify.Create {
+			strings.Contains(ev.Name, "lab2") && ev.Op == Create {
 			ok = true
 		}
 	case <-time.After(time.Second * 10):
@@ -139,8 +142,8 @@ T3:
 	for {
 		select {
 		case ev := <-events:
-			if ev.Op == fsnotify.Create ||
-				ev.Op == fsnotify.Remove {
+			if ev.Op == Create ||
+				ev.Op == Remove {
 				actions++
 			}
 		case <-time.After(time.Millisecond * 60):
@@ -150,6 +153,459 @@ T3:
 	require.Condition(func() bool { return actions > 2 })
 }
 
+func TestWouldWatch(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-example")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "lab1"), 0777))
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "node_modules"), 0777))
+
+	ptrn := filepath.Join(rootDirectory, "node_*")
+	watcher := New(Notify(func(Event) {}), Exclude(ptrn))
+	defer watcher.Stop()
+
+	ok, reason := watcher.WouldWatch(filepath.Join(rootDirectory, "lab1"))
+	require.True(ok)
+	require.Empty(reason)
+
+	ok, reason = watcher.WouldWatch(filepath.Join(rootDirectory, "node_modules"))
+	require.False(ok)
+	require.Equal("excluded by pattern "+ptrn, reason)
+
+	ok, reason = watcher.WouldWatch(filepath.Join(rootDirectory, "lab2"))
+	require.False(ok)
+	require.Equal("does not exist", reason)
+}
+
+func TestRelativeEvents(t *testing.T) {
+	require := require.New(t)
+
+	root1, err := ioutil.TempDir(os.TempDir(), "dirwatch-example")
+	require.NoError(err)
+	defer os.RemoveAll(root1)
+	root2, err := ioutil.TempDir(os.TempDir(), "dirwatch-example")
+	require.NoError(err)
+	defer os.RemoveAll(root2)
+	require.NoError(os.Mkdir(filepath.Join(root1, "lab1"), 0777))
+	require.NoError(os.Mkdir(filepath.Join(root2, "lab2"), 0777))
+
+	var events = make(chan Event, 100)
+	notify := func(ev Event) {
+		events <- ev
+	}
+
+	watcher := New(Notify(notify), RelativeEvents())
+	defer watcher.Stop()
+	watcher.Add(root1, true)
+	watcher.Add(root2, true)
+	<-time.After(time.Millisecond * 100)
+
+	next := func(name string) Event {
+		for {
+			select {
+			case ev := <-events:
+				if ev.Name == name {
+					return ev
+				}
+			case <-time.After(time.Second * 5):
+				require.FailNow("missing event for " + name)
+			}
+		}
+	}
+
+	fp := filepath.Join(root1, "lab1", "sample.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0777))
+	ev := next(fp)
+	require.Equal(root1, ev.Root)
+	require.Equal(filepath.Join("lab1", "sample.txt"), ev.RelName)
+
+	fp = filepath.Join(root2, "lab2", "sample.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0777))
+	ev = next(fp)
+	require.Equal(root2, ev.Root)
+	require.Equal(filepath.Join("lab2", "sample.txt"), ev.RelName)
+}
+
+func TestNotifyRetry(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-example")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var (
+		mx    sync.Mutex
+		calls = make(map[string]int)
+	)
+	notify := func(ev Event) error {
+		mx.Lock()
+		defer mx.Unlock()
+		calls[filepath.Base(ev.Name)]++
+		if filepath.Base(ev.Name) == "flaky.txt" && calls["flaky.txt"] < 3 {
+			return fmt.Errorf("transient failure")
+		}
+		if filepath.Base(ev.Name) == "broken.txt" {
+			return fmt.Errorf("permanent failure")
+		}
+		return nil
+	}
+
+	watcher := New(
+		NotifyErr(notify),
+		NotifyRetry(3, time.Millisecond*10),
+		Logger(func(...interface{}) {}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "flaky.txt"), nil, 0777))
+	<-time.After(time.Millisecond * 300)
+	mx.Lock()
+	require.Equal(3, calls["flaky.txt"])
+	mx.Unlock()
+	select {
+	case err := <-watcher.Errors():
+		require.FailNow("unexpected error", err)
+	default:
+	}
+
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "broken.txt"), nil, 0777))
+	select {
+	case err := <-watcher.Errors():
+		require.Contains(err.Error(), "broken.txt")
+		require.Contains(err.Error(), "permanent failure")
+	case <-time.After(time.Second * 5):
+		require.FailNow("exhausted retries not reported")
+	}
+	mx.Lock()
+	require.Equal(3, calls["broken.txt"])
+	mx.Unlock()
+}
+
+func TestNormalizeUnicode(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-example")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), NormalizeUnicode(norm.NFC))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	composed := "caf\u00e9.txt"
+	decomposed := "cafe\u0301.txt"
+	require.NotEqual(composed, decomposed)
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, decomposed), nil, 0777))
+
+	select {
+	case ev := <-events:
+		require.Equal(filepath.Join(rootDirectory, composed), ev.Name)
+	case <-time.After(time.Second * 5):
+		require.FailNow("no event")
+	}
+}
+
+func TestNestedAddRemove(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-example")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	dirA := filepath.Join(rootDirectory, "a")
+	dirB := filepath.Join(dirA, "b")
+	dirC := filepath.Join(dirA, "c")
+	require.NoError(os.MkdirAll(dirB, 0777))
+	require.NoError(os.MkdirAll(dirC, 0777))
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }))
+	defer watcher.Stop()
+
+	// writes a file into each directory and returns the roots of the
+	// events received, by directory
+	touch := func(name string) map[string]string {
+		for _, dir := range []string{dirA, dirB, dirC} {
+			require.NoError(ioutil.WriteFile(filepath.Join(dir, name), nil, 0777))
+		}
+		roots := make(map[string]string)
+		for {
+			select {
+			case ev := <-events:
+				roots[filepath.Dir(ev.Name)] = ev.Root
+			case <-time.After(time.Millisecond * 300):
+				return roots
+			}
+		}
+	}
+
+	watcher.Add(dirA, true)
+	watcher.Add(dirB, true)
+	<-time.After(time.Millisecond * 100)
+	require.Equal(map[string]string{dirA: dirA, dirB: dirB, dirC: dirA}, touch("1.txt"))
+
+	// b stays watched, as a part of a
+	require.NoError(watcher.Remove(dirB))
+	require.Equal(map[string]string{dirA: dirA, dirB: dirA, dirC: dirA}, touch("2.txt"))
+
+	// b stays watched, as a root of its own
+	watcher.Add(dirB, true)
+	require.NoError(watcher.Remove(dirA))
+	require.Equal(map[string]string{dirB: dirB}, touch("3.txt"))
+
+	require.NoError(watcher.Remove(dirB))
+	require.Empty(touch("4.txt"))
+}
+
+func TestAddContext(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-example")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	watcher := New(Notify(func(Event) {}))
+	defer watcher.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(context.Canceled, watcher.AddContext(ctx, rootDirectory, true))
+
+	// keep the agent busy, so the add can not get through
+	busy := make(chan struct{})
+	release := make(chan struct{})
+	go watcher.call(func(backend) {
+		close(busy)
+		<-release
+	})
+	<-busy
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	start := time.Now()
+	err = watcher.AddContext(ctx, rootDirectory, true)
+	require.Equal(context.DeadlineExceeded, err)
+	require.True(time.Since(start) < time.Second)
+	close(release)
+
+	// the abandoned add is dropped
+	require.NoError(watcher.call(func(backend) {
+		require.NotContains(watcher.paths, rootDirectory)
+	}))
+
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+	require.NoError(watcher.call(func(backend) {
+		require.Contains(watcher.paths, rootDirectory)
+	}))
+}
+
+func TestOnRawEvent(t *testing.T) {
+	require := require.New(t)
+
+	raw := make(chan fsnotify.Event, 10)
+	events := make(chan Event, 10)
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		Exclude("**/*.tmp"),
+		OnRawEvent(func(ev fsnotify.Event) { raw <- ev }),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	name := filepath.Join(os.TempDir(), "dirwatch-raw.tmp")
+	fake.events <- fsnotify.Event{Name: name, Op: fsnotify.Create | fsnotify.Chmod}
+
+	select {
+	case ev := <-raw:
+		require.Equal(name, ev.Name)
+		require.Equal(fsnotify.Create|fsnotify.Chmod, ev.Op)
+	case <-time.After(time.Second):
+		require.Fail("raw event not received")
+	}
+	select {
+	case ev := <-events:
+		require.Failf("excluded event delivered", "%v", ev)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestAddWithTag(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-tag")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	nested := filepath.Join(rootDirectory, "nested")
+	require.NoError(os.Mkdir(nested, 0777))
+
+	type handle struct{ id int }
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }))
+	defer watcher.Stop()
+
+	watcher.AddWithTag(rootDirectory, true, handle{1})
+	watcher.AddWithTag(nested, false, handle{2})
+	<-time.After(time.Millisecond * 50)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "a.txt"), []byte("DATA"), 0666))
+	require.NoError(ioutil.WriteFile(filepath.Join(nested, "b.txt"), []byte("DATA"), 0666))
+
+	tags := make(map[string]interface{})
+T1:
+	for {
+		select {
+		case ev := <-events:
+			tags[filepath.Base(ev.Name)] = ev.Tag
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(handle{1}, tags["a.txt"])
+	require.Equal(handle{2}, tags["b.txt"])
+}
+
+func TestWatchFilter(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-filter")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	for _, dir := range []string{"ab/abcd", "ab/abc/ab", "abc/ab", "cd"} {
+		require.NoError(os.MkdirAll(filepath.Join(rootDirectory, dir), 0777))
+	}
+
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		Exclude("**/cd"),
+		WatchFilter(func(path string, info os.FileInfo) bool {
+			return path == rootDirectory || len(info.Name())%2 == 0
+		}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	require.True(fake.watched(rootDirectory))
+	require.True(fake.watched(filepath.Join(rootDirectory, "ab")))
+	require.True(fake.watched(filepath.Join(rootDirectory, "ab", "abcd")))
+	require.False(fake.watched(filepath.Join(rootDirectory, "ab", "abc")))
+	require.False(fake.watched(filepath.Join(rootDirectory, "ab", "abc", "ab")))
+	require.False(fake.watched(filepath.Join(rootDirectory, "abc")))
+	require.False(fake.watched(filepath.Join(rootDirectory, "abc", "ab")))
+	require.False(fake.watched(filepath.Join(rootDirectory, "cd")))
+
+	ok, reason := watcher.WouldWatch(filepath.Join(rootDirectory, "abc"))
+	require.False(ok)
+	require.Equal("rejected by watch filter", reason)
+}
+
+func TestHeartbeat(t *testing.T) {
+	require := require.New(t)
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), Heartbeat(time.Millisecond*50))
+	defer watcher.Stop()
+
+	start := time.Now()
+	var beats []time.Time
+	for len(beats) < 4 {
+		select {
+		case ev := <-events:
+			require.Equal(Beat, ev.Op)
+			require.Empty(ev.Name)
+			beats = append(beats, ev.Time)
+		case <-time.After(time.Second):
+			require.Fail("no heartbeat")
+		}
+	}
+	elapsed := beats[3].Sub(start)
+	require.True(elapsed >= time.Millisecond*150, elapsed)
+	require.True(elapsed < time.Millisecond*600, elapsed)
+}
+
+func TestIsRecursive(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-isrecursive")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	deep := filepath.Join(rootDirectory, "deep")
+	flat := filepath.Join(rootDirectory, "flat")
+	require.NoError(os.MkdirAll(filepath.Join(deep, "sub"), 0777))
+	require.NoError(os.MkdirAll(filepath.Join(flat, "sub"), 0777))
+
+	watcher := New(Notify(func(Event) {}))
+	defer watcher.Stop()
+	watcher.Add(deep, true)
+	watcher.Add(flat, false)
+	<-time.After(time.Millisecond * 100)
+
+	recursive, known := watcher.IsRecursive(deep)
+	require.True(known)
+	require.True(recursive)
+
+	recursive, known = watcher.IsRecursive(filepath.Join(deep, "sub"))
+	require.True(known)
+	require.True(recursive)
+
+	recursive, known = watcher.IsRecursive(flat)
+	require.True(known)
+	require.False(recursive)
+
+	_, known = watcher.IsRecursive(filepath.Join(flat, "sub"))
+	require.False(known)
+}
+
+func TestExcludeFunc(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-excludefunc")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	quiet := filepath.Join(rootDirectory, "var")
+	require.NoError(os.Mkdir(quiet, 0777))
+	fp := filepath.Join(quiet, "app.log")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		ExcludeFunc(func(ev Event) bool {
+			return ev.Op == Chmod && strings.HasPrefix(ev.Name, quiet)
+		}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	require.NoError(os.Chmod(fp, 0600))
+	<-time.After(time.Millisecond * 50)
+	f, err := os.OpenFile(fp, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(err)
+	_, err = f.Write([]byte("MORE"))
+	require.NoError(err)
+	require.NoError(f.Close())
+
+	var ops Op
+T1:
+	for {
+		select {
+		case ev := <-events:
+			require.Equal(fp, ev.Name)
+			ops |= ev.Op
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(Write, ops)
+}
+
 func prep() string {
 	rootDirectory := filepath.Join(os.TempDir(), "dirwatch-example-exclude")
 	if err := os.RemoveAll(rootDirectory); err != nil {
@@ -170,6 +626,7 @@ func ExampleWatcher_simple() {
 	watcher := New(Notify(notify))
 	defer watcher.Stop()
 	watcher.Add(dir, true)
+	<-time.After(time.Millisecond * 50)
 
 	ioutil.WriteFile(filepath.Join(dir, "text.txt"), nil, 0777)
 	<-time.After(time.Millisecond * 300)
@@ -294,3 +751,178 @@ func ExampleWatcher_simpleExclude() {
 	// Output:
 	// 4
 }
+
+func TestDirChurnAddTraffic(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-churn")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+	time.Sleep(time.Millisecond * 100)
+
+	var dirs []string
+	for i := 0; i < 5; i++ {
+		dir := filepath.Join(rootDirectory, fmt.Sprintf("sub%d", i))
+		require.NoError(os.Mkdir(dir, 0755))
+		dirs = append(dirs, dir)
+		fake.events <- fsnotify.Event{Name: dir, Op: fsnotify.Create}
+	}
+	for _, dir := range dirs {
+		for fake.addCount(dir) == 0 {
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		for _, dir := range dirs {
+			fake.events <- fsnotify.Event{Name: dir, Op: fsnotify.Chmod}
+		}
+	}
+	time.Sleep(time.Millisecond * 300)
+	require.NoError(watcher.call(func(backend) {}))
+
+	watcher.adds.mu.Lock()
+	pushed := watcher.adds.seq
+	watcher.adds.mu.Unlock()
+	// the root, then each new directory once
+	require.Equal(uint64(1+len(dirs)), pushed)
+	for _, dir := range dirs {
+		require.Equal(1, fake.addCount(dir))
+	}
+}
+
+func TestSetRecursive(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-setrecursive")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 2, 2)
+	deep := filepath.Join(rootDirectory, "d1", "d1")
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }))
+	defer watcher.Stop()
+	require.Error(watcher.SetRecursive(rootDirectory, true))
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, false))
+
+	deepEvent := func() bool {
+		fp := filepath.Join(deep, "data.txt")
+		require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+		seen := false
+		for {
+			select {
+			case ev := <-events:
+				seen = seen || ev.Name == fp
+			case <-time.After(time.Millisecond * 300):
+				return seen
+			}
+		}
+	}
+	waitRecursive := func(want bool) {
+		for {
+			recursive, known := watcher.IsRecursive(deep)
+			if recursive == want && known == want {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+	require.False(deepEvent())
+
+	for i := 0; i < 2; i++ {
+		require.NoError(watcher.SetRecursive(rootDirectory, true))
+	}
+	waitRecursive(true)
+	require.True(deepEvent())
+
+	for i := 0; i < 2; i++ {
+		require.NoError(watcher.SetRecursive(rootDirectory, false))
+	}
+	waitRecursive(false)
+	require.False(deepEvent())
+	recursive, known := watcher.IsRecursive(rootDirectory)
+	require.False(recursive)
+	require.True(known)
+}
+
+func TestAddsBeforeEvents(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-fairness")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	fake := newFakeBackend()
+	var (
+		mx     sync.Mutex
+		missed []string
+		files  = make(map[string]bool)
+	)
+	watcher := New(
+		Notify(func(ev Event) {
+			mx.Lock()
+			defer mx.Unlock()
+			files[ev.Name] = true
+		}),
+		OnRawEvent(func(ev fsnotify.Event) {
+			if filepath.Base(ev.Name) != "new.txt" {
+				return
+			}
+			// the directory must be watched before the event of its file
+			// comes in, or a real backend would have missed it
+			if dir := filepath.Dir(ev.Name); fake.addCount(dir) == 0 {
+				mx.Lock()
+				missed = append(missed, dir)
+				mx.Unlock()
+			}
+		}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+
+	load := filepath.Join(rootDirectory, "load.txt")
+	require.NoError(ioutil.WriteFile(load, []byte("DATA"), 0644))
+	var created []string
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 20; j++ {
+			fake.events <- fsnotify.Event{Name: load, Op: fsnotify.Write}
+		}
+		dir := filepath.Join(rootDirectory, fmt.Sprintf("sub%d", i))
+		require.NoError(os.Mkdir(dir, 0755))
+		fp := filepath.Join(dir, "new.txt")
+		require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+		fake.events <- fsnotify.Event{Name: dir, Op: fsnotify.Create}
+		fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Create}
+		created = append(created, fp)
+	}
+
+	deadline := time.After(time.Second * 10)
+	for {
+		mx.Lock()
+		n := 0
+		for _, fp := range created {
+			if files[fp] {
+				n++
+			}
+		}
+		mx.Unlock()
+		if n == len(created) {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("only %d of %d files were reported", n, len(created))
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+	mx.Lock()
+	defer mx.Unlock()
+	require.Empty(missed)
+}
@@ -0,0 +1,114 @@
+// Package dirwatchprom exposes the counters of dirwatch watchers as
+// Prometheus metrics, so the core package does not depend on Prometheus.
+package dirwatchprom
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/dc0d/dirwatch"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector collects the metrics of a set of watchers, labelled by the
+// names they were added with, from their Stats on every scrape.
+type Collector struct {
+	mx       sync.Mutex
+	watchers map[string]*dirwatch.Watcher
+
+	watched    *prometheus.Desc
+	delivered  *prometheus.Desc
+	dropped    *prometheus.Desc
+	errors     *prometheus.Desc
+	queueDepth *prometheus.Desc
+	goroutines *prometheus.Desc
+	state      *prometheus.Desc
+	muted      *prometheus.Desc
+}
+
+// NewCollector creates a Collector and registers it with reg.
+func NewCollector(reg prometheus.Registerer) (*Collector, error) {
+	labels := []string{"watcher"}
+	c := &Collector{
+		watchers: make(map[string]*dirwatch.Watcher),
+		watched: prometheus.NewDesc("dirwatch_watched_paths",
+			"Number of watched paths.", labels, nil),
+		delivered: prometheus.NewDesc("dirwatch_events_delivered_total",
+			"Number of events sent for delivery.", labels, nil),
+		dropped: prometheus.NewDesc("dirwatch_events_dropped_total",
+			"Number of events dropped by the filters.", labels, nil),
+		errors: prometheus.NewDesc("dirwatch_errors_total",
+			"Number of errors reported.", labels, nil),
+		queueDepth: prometheus.NewDesc("dirwatch_queue_depth",
+			"Number of pending adds and of undelivered events.", append(labels, "queue"), nil),
+		goroutines: prometheus.NewDesc("dirwatch_goroutines",
+			"Number of goroutines delivering events and walking directories.", labels, nil),
+		state: prometheus.NewDesc("dirwatch_state_entries",
+			"Number of per-path entries kept.", labels, nil),
+		muted: prometheus.NewDesc("dirwatch_events_muted_total",
+			"Number of events dropped because their path was muted.", labels, nil),
+	}
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add makes the metrics of watcher to be collected, labelled with name.
+// Adding a name again replaces its watcher.
+func (c *Collector) Add(name string, watcher *dirwatch.Watcher) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.watchers[name] = watcher
+}
+
+// Remove stops collecting the metrics of the watcher added as name.
+func (c *Collector) Remove(name string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.watchers, name)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.watched, c.delivered, c.dropped, c.errors,
+		c.queueDepth, c.goroutines, c.state, c.muted,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mx.Lock()
+	watchers := make(map[string]*dirwatch.Watcher, len(c.watchers))
+	for name, w := range c.watchers {
+		watchers[name] = w
+	}
+	c.mx.Unlock()
+	names := make([]string, 0, len(watchers))
+	for name := range watchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		st := watchers[name].Stats()
+		gauge := func(d *prometheus.Desc, v int, labels ...string) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(v), append([]string{name}, labels...)...)
+		}
+		counter := func(d *prometheus.Desc, v uint64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v), name)
+		}
+		gauge(c.watched, st.Watched)
+		counter(c.delivered, st.Delivered)
+		counter(c.dropped, st.Filtered)
+		counter(c.errors, st.Errors)
+		gauge(c.queueDepth, st.PendingAdds, "adds")
+		gauge(c.queueDepth, st.Undelivered, "deliveries")
+		gauge(c.goroutines, st.Goroutines)
+		gauge(c.state, st.State)
+		counter(c.muted, st.Muted)
+	}
+}
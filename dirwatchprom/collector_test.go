@@ -0,0 +1,103 @@
+package dirwatchprom
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dc0d/dirwatch"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-prom")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "sub"), 0755))
+
+	var events = make(chan dirwatch.Event, 100)
+	watcher := dirwatch.New(
+		dirwatch.Notify(func(ev dirwatch.Event) { events <- ev }),
+		dirwatch.ExcludeFunc(func(ev dirwatch.Event) bool {
+			return filepath.Ext(ev.Name) == ".tmp"
+		}))
+	defer watcher.Stop()
+	idle := dirwatch.New(dirwatch.Notify(func(dirwatch.Event) {}))
+	defer idle.Stop()
+
+	reg := prometheus.NewRegistry()
+	c, err := NewCollector(reg)
+	require.NoError(err)
+	c.Add("busy", watcher)
+	c.Add("idle", idle)
+
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "a.tmp"), []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "a.txt"), []byte("DATA"), 0644))
+	select {
+	case <-events:
+	case <-time.After(time.Second * 5):
+		require.FailNow("event not received")
+	}
+	<-time.After(time.Millisecond * 200)
+
+	// the root and its sub-directory
+	require.NoError(testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP dirwatch_watched_paths Number of watched paths.
+# TYPE dirwatch_watched_paths gauge
+dirwatch_watched_paths{watcher="busy"} 2
+dirwatch_watched_paths{watcher="idle"} 0
+`), "dirwatch_watched_paths"))
+	require.NoError(testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP dirwatch_queue_depth Number of pending adds and of undelivered events.
+# TYPE dirwatch_queue_depth gauge
+dirwatch_queue_depth{queue="adds",watcher="busy"} 0
+dirwatch_queue_depth{queue="adds",watcher="idle"} 0
+dirwatch_queue_depth{queue="deliveries",watcher="busy"} 0
+dirwatch_queue_depth{queue="deliveries",watcher="idle"} 0
+`), "dirwatch_queue_depth"))
+
+	values := gather(t, reg)
+	require.True(values["dirwatch_events_delivered_total/busy"] >= 1)
+	require.True(values["dirwatch_events_dropped_total/busy"] >= 1)
+	require.Zero(values["dirwatch_events_delivered_total/idle"])
+	require.Zero(values["dirwatch_errors_total/busy"])
+
+	c.Remove("idle")
+	require.Equal(1, testutil.CollectAndCount(c, "dirwatch_watched_paths"))
+}
+
+// gather returns the values of the metrics of reg, by name and watcher,
+// joined by a slash.
+func gather(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	res := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			var watcher string
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "watcher" {
+					watcher = label.GetValue()
+				}
+			}
+			var v float64
+			switch {
+			case m.GetCounter() != nil:
+				v = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				v = m.GetGauge().GetValue()
+			}
+			res[family.GetName()+"/"+watcher] = v
+		}
+	}
+	return res
+}
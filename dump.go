@@ -0,0 +1,90 @@
+package dirwatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Dump writes a human-readable report of the state of the watcher to w:
+// the added roots, the watched paths, the exclude patterns, those of
+// ExcludePerRoot included, the counters of Stats and the backend in use. It is meant to be attached to bug reports. The
+// state is collected on the agent goroutine, so it is consistent.
+func (dw *Watcher) Dump(w io.Writer) error {
+	var buf bytes.Buffer
+	err := dw.call(func(watcher backend) {
+		fmt.Fprintf(&buf, "backend: %T\n", watcher)
+
+		roots := make([]string, 0, len(dw.roots))
+		for p := range dw.roots {
+			roots = append(roots, p)
+		}
+		sort.Strings(roots)
+		fmt.Fprintf(&buf, "roots: %d\n", len(roots))
+		for _, p := range roots {
+			fmt.Fprintf(&buf, "  %s recursive=%v\n", p, dw.roots[p].recursive)
+		}
+
+		paths := make([]string, 0, len(dw.paths))
+		for p := range dw.paths {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		fmt.Fprintf(&buf, "paths: %d\n", len(paths))
+		for _, p := range paths {
+			wp := dw.paths[p]
+			fmt.Fprintf(&buf, "  %s recursive=%v dir=%v\n", p, wp.recursive, wp.dir)
+		}
+
+		fmt.Fprintf(&buf, "exclude: %d\n", len(dw.exclude))
+		for _, ptrn := range dw.exclude {
+			fmt.Fprintf(&buf, "  %s\n", ptrn)
+		}
+		if len(dw.rootExclude) > 0 {
+			excluding := make([]string, 0, len(dw.rootExclude))
+			for root := range dw.rootExclude {
+				excluding = append(excluding, root)
+			}
+			sort.Strings(excluding)
+			fmt.Fprintf(&buf, "exclude per root: %d\n", len(excluding))
+			for _, root := range excluding {
+				for _, ptrn := range dw.rootExclude[root] {
+					fmt.Fprintf(&buf, "  %s %s\n", root, ptrn)
+				}
+			}
+		}
+		if len(dw.confine) > 0 {
+			fmt.Fprintf(&buf, "confine: %d\n", len(dw.confine))
+			for _, p := range dw.confine {
+				fmt.Fprintf(&buf, "  %s\n", p)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	stats := dw.Stats()
+	fmt.Fprintf(&buf, "stats:\n")
+	fmt.Fprintf(&buf, "  muted=%d\n", stats.Muted)
+	fmt.Fprintf(&buf, "  state=%d\n", stats.State)
+	fmt.Fprintf(&buf, "  goroutines=%d\n", stats.Goroutines)
+	fmt.Fprintf(&buf, "  watched=%d\n", stats.Watched)
+	fmt.Fprintf(&buf, "  delivered=%d\n", stats.Delivered)
+	fmt.Fprintf(&buf, "  filtered=%d\n", stats.Filtered)
+	fmt.Fprintf(&buf, "  errors=%d\n", stats.Errors)
+	fmt.Fprintf(&buf, "  pending_adds=%d\n", stats.PendingAdds)
+	fmt.Fprintf(&buf, "  undelivered=%d\n", stats.Undelivered)
+	dirs := make([]string, 0, len(stats.Throttled))
+	for dir := range stats.Throttled {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		fmt.Fprintf(&buf, "  throttled %s=%d\n", dir, stats.Throttled[dir])
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
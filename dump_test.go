@@ -0,0 +1,42 @@
+package dirwatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-dump")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	watcher := New(
+		Notify(func(Event) {}),
+		Exclude("**/*.tmp"),
+		ExcludePerRoot(rootDirectory, "build"))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 50)
+
+	var buf bytes.Buffer
+	require.NoError(watcher.Dump(&buf))
+	dump := buf.String()
+	require.Contains(dump, "backend: dirwatch.fsnotifyBackend")
+	require.Contains(dump, rootDirectory+" recursive=true")
+	require.Contains(dump, "**/*.tmp")
+	require.Contains(dump, rootDirectory+" build")
+	require.Contains(dump, "muted=0")
+	require.Contains(dump, "watched=1")
+	require.Contains(dump, "errors=0")
+	require.Contains(dump, "pending_adds=0")
+
+	watcher.Stop()
+	require.Equal(ErrWatcherStopped, watcher.Dump(&buf))
+}
@@ -0,0 +1,33 @@
+package dirwatch
+
+// EditorTempFiles are the base name patterns HideEditorTempFiles uses by
+// default:
+//
+//	*.swp, *.swo, *.swx, 4913  vim swap files and its write probe
+//	*~                         vim and emacs backups
+//	.#*, #*#                   emacs lock and auto-save files
+//	*.tmp                      atomic saves of VS Code and others
+//	*___jb_tmp___, *___jb_old___  JetBrains IDEs safe writes
+var EditorTempFiles = []string{
+	"*.swp", "*.swo", "*.swx", "4913",
+	"*~",
+	".#*", "#*#",
+	"*.tmp",
+	"*___jb_tmp___", "*___jb_old___",
+}
+
+// HideEditorTempFiles drops the events of the temporary files editors use
+// for swap files, backups and atomic saves, so an atomic save is reported
+// as the events of the saved file only. patterns are matched against base
+// names using filepath.Match; without patterns, EditorTempFiles is used.
+// The temporary files are excluded like with Exclude.
+func HideEditorTempFiles(patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = EditorTempFiles
+	}
+	return func(opt *options) {
+		for _, ptrn := range patterns {
+			opt.exclude = append(opt.exclude, anyDepth+ptrn)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHideEditorTempFiles(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-editortemp")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	fp := filepath.Join(rootDirectory, "notes.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("V1"), 0644))
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), HideEditorTempFiles())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	// a vim save: swap file, write probe, backup by rename, new file
+	swp := filepath.Join(rootDirectory, ".notes.txt.swp")
+	require.NoError(ioutil.WriteFile(swp, []byte("SWAP"), 0644))
+	probe := filepath.Join(rootDirectory, "4913")
+	require.NoError(ioutil.WriteFile(probe, nil, 0644))
+	require.NoError(os.Remove(probe))
+	backup := fp + "~"
+	require.NoError(os.Rename(fp, backup))
+	require.NoError(ioutil.WriteFile(fp, []byte("V2"), 0644))
+	require.NoError(os.Remove(backup))
+	require.NoError(os.Remove(swp))
+
+	var ops Op
+T1:
+	for {
+		select {
+		case ev := <-events:
+			require.Equal(fp, ev.Name)
+			ops |= ev.Op
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(Create, ops&Create)
+}
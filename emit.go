@@ -0,0 +1,44 @@
+package dirwatch
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Emit injects ev into the watcher as if the file system had reported it,
+// for integration tests, replaying events or triggering processing by hand
+// without touching the disk. It goes through the exclude patterns, the
+// filters and the delivery options like any other event, but only to be
+// delivered: it never registers or drops watches, nor changes what the
+// watcher knows of the tree. A relative ev.Name is made absolute, and a
+// zero ev.Time is set to the time ev is taken in. It returns once ev has
+// been through the filters, or an error if the watcher is stopped.
+func (dw *Watcher) Emit(ev Event) error {
+	if ev.Name == "" {
+		return errors.New("emitted event has no name")
+	}
+	name, err := filepath.Abs(ev.Name)
+	if err != nil {
+		return err
+	}
+	ev.Name = name
+	return dw.call(func(backend) { dw.emit(ev) })
+}
+
+// emit runs ev through the filters into delivery, leaving the watches
+// alone. It runs on the agent goroutine.
+func (dw *Watcher) emit(ev Event) {
+	if dw.draining {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = dw.clock.Now()
+	}
+	dw.trace(ev.Name, "emitted", ev.Op)
+	ev, inf, _, ok := dw.examine(ev)
+	if !ok {
+		return
+	}
+	dw.filterEvent(ev, inf, inf != nil && inf.IsDir())
+}
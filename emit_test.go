@@ -0,0 +1,78 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmit(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-emit")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	dir := filepath.Join(rootDirectory, "dir")
+	require.NoError(os.Mkdir(dir, 0755))
+
+	fake := newFakeBackend()
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		Exclude("**/*.tmp"),
+		ExcludeFunc(func(ev Event) bool { return ev.Op == Chmod }),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+	for fake.addCount(dir) == 0 {
+		<-time.After(time.Millisecond * 10)
+	}
+
+	// the filters apply to emitted events too
+	require.NoError(watcher.Emit(Event{Name: filepath.Join(rootDirectory, "a.tmp"), Op: Write}))
+	require.NoError(watcher.Emit(Event{Name: filepath.Join(rootDirectory, "a.txt"), Op: Chmod}))
+
+	// the file does not have to exist
+	fp := filepath.Join(rootDirectory, "a.txt")
+	require.NoError(watcher.Emit(Event{Name: fp, Op: Write}))
+	select {
+	case ev := <-events:
+		require.Equal(fp, ev.Name)
+		require.Equal(Write, ev.Op)
+		require.Equal(rootDirectory, ev.Root)
+		require.False(ev.Time.IsZero())
+	case <-time.After(time.Second * 5):
+		require.FailNow("emitted event not delivered")
+	}
+
+	// nor do the watches change
+	sub := filepath.Join(rootDirectory, "sub")
+	require.NoError(os.Mkdir(sub, 0755))
+	require.NoError(watcher.Emit(Event{Name: sub, Op: Create}))
+	require.NoError(watcher.Emit(Event{Name: dir, Op: Remove}))
+	for i := 0; i < 2; i++ {
+		select {
+		case <-events:
+		case <-time.After(time.Second * 5):
+			require.FailNow("emitted event not delivered")
+		}
+	}
+	<-time.After(time.Millisecond * 100)
+	var subWatched, dirWatched bool
+	require.NoError(watcher.call(func(backend) {
+		_, subWatched = watcher.paths[sub]
+		_, dirWatched = watcher.paths[dir]
+	}))
+	require.False(subWatched)
+	require.True(dirWatched)
+	require.Equal(0, fake.addCount(sub))
+	require.Len(events, 0)
+
+	watcher.Stop()
+	require.Error(watcher.Emit(Event{Name: fp, Op: Write}))
+}
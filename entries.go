@@ -0,0 +1,62 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CountEntries calls fn with the number of regular files in a watched
+// directory each time it changes, e.g. to apply backpressure on a queue
+// directory. The count is kept up to date from Create, Remove and Rename
+// events; a directory is listed only for its first event. fn is called on
+// the agent goroutine, so it must not block.
+func CountEntries(fn func(path string, count int)) Option {
+	return func(opt *options) {
+		opt.countEntries = fn
+	}
+}
+
+// countEntry updates the number of regular files in the directory of name,
+// with inf being the current info of name, if it exists.
+func (dw *Watcher) countEntry(ev Event, name string, inf os.FileInfo) {
+	if dw.countEntries == nil {
+		return
+	}
+	if wp, ok := dw.paths[name]; ok && wp.dir && ev.Op&(Remove|Rename) != 0 {
+		delete(dw.entries, name)
+	}
+	dir := filepath.Dir(name)
+	if wp, ok := dw.paths[dir]; !ok || !wp.dir {
+		return
+	}
+
+	files, ok := dw.entries[dir]
+	if !ok {
+		list, err := ioutil.ReadDir(dir)
+		if err != nil {
+			dw.fail(err)
+			return
+		}
+		files = make(map[string]struct{})
+		for _, v := range list {
+			if v.Mode().IsRegular() {
+				files[filepath.Join(dir, v.Name())] = struct{}{}
+			}
+		}
+		dw.entries[dir] = files
+		dw.countEntries(dir, len(files))
+		return
+	}
+
+	before := len(files)
+	switch {
+	case ev.Op&(Remove|Rename) != 0 && inf == nil:
+		delete(files, name)
+	case inf != nil && inf.Mode().IsRegular():
+		files[name] = struct{}{}
+	}
+	if len(files) != before {
+		dw.countEntries(dir, len(files))
+	}
+}
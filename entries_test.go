@@ -0,0 +1,60 @@
+package dirwatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountEntries(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-entries")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "existing.job"), nil, 0777))
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "sub"), 0777))
+
+	var counts = make(chan int, 100)
+	watcher := New(
+		Notify(func(Event) {}),
+		CountEntries(func(path string, count int) {
+			if path != rootDirectory {
+				count = -100
+			}
+			counts <- count
+		}))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, false))
+
+	last := func() (count int) {
+		count = -1
+		for {
+			select {
+			case count = <-counts:
+			case <-time.After(time.Millisecond * 300):
+				return
+			}
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, fmt.Sprintf("%d.job", i)), nil, 0777))
+	}
+	require.Equal(4, last())
+
+	require.NoError(os.Remove(filepath.Join(rootDirectory, "1.job")))
+	require.NoError(os.Remove(filepath.Join(rootDirectory, "existing.job")))
+	require.Equal(2, last())
+
+	// writes and directories do not change the count
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "0.job"), []byte("DATA"), 0777))
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "sub2"), 0777))
+	require.Equal(-1, last())
+}
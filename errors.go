@@ -0,0 +1,58 @@
+package dirwatch
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// The kinds of errors the watcher reports, on Errors and from the methods
+// returning errors, to tell apart using errors.Is. The reported errors
+// wrap their cause too, like an *os.PathError or a syscall.Errno, for
+// errors.Is and errors.As.
+var (
+	// ErrWatchLimitReached is the kind of the errors of paths the backend
+	// can not watch because it ran out of watches, like inotify does at
+	// fs.inotify.max_user_watches.
+	ErrWatchLimitReached = errors.New("watch limit reached")
+	// ErrRootNotFound is the kind of the errors of added roots that do not
+	// exist, with StrictRoots.
+	ErrRootNotFound = errors.New("root not found")
+	// ErrNotDirectory is the kind of the errors of added roots that are not
+	// directories, with StrictRoots.
+	ErrNotDirectory = errors.New("not a directory")
+	// ErrWatcherStopped is returned by the methods called after Stop.
+	ErrWatcherStopped = errors.New("watcher is stopped")
+	// ErrPermission is the kind of the errors of paths that can not be
+	// watched or listed for lack of permission.
+	ErrPermission = errors.New("permission denied")
+)
+
+// kindError is an error of one of the exported kinds, wrapping its cause,
+// which describes it.
+type kindError struct {
+	kind  error
+	cause error
+}
+
+func (e *kindError) Error() string        { return e.cause.Error() }
+func (e *kindError) Unwrap() error        { return e.cause }
+func (e *kindError) Cause() error         { return e.cause }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// classify gives err the kind of its cause, if it has one.
+func classify(err error) error {
+	var kind error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.ENOSPC):
+		kind = ErrWatchLimitReached
+	case errors.Is(err, os.ErrPermission):
+		kind = ErrPermission
+	default:
+		return err
+	}
+	return &kindError{kind: kind, cause: errors.WithMessage(err, kind.Error())}
+}
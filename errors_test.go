@@ -0,0 +1,69 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKinds(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-errors")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	full := filepath.Join(rootDirectory, "full")
+	private := filepath.Join(rootDirectory, "private")
+	file := filepath.Join(rootDirectory, "file.txt")
+	require.NoError(os.Mkdir(full, 0755))
+	require.NoError(os.Mkdir(private, 0755))
+	require.NoError(ioutil.WriteFile(file, []byte("DATA"), 0644))
+
+	fake := newFakeBackend()
+	fake.addErr = func(path string) error {
+		switch path {
+		case full:
+			return syscall.ENOSPC
+		case private:
+			return &os.PathError{Op: "inotify_add_watch", Path: path, Err: syscall.EACCES}
+		}
+		return nil
+	}
+	watcher := New(
+		Notify(func(Event) {}),
+		StrictRoots(),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+	add := func(path string) error {
+		return watcher.AddContext(context.Background(), path, false)
+	}
+
+	err = add(full)
+	require.True(errors.Is(err, ErrWatchLimitReached))
+	require.True(errors.Is(err, syscall.ENOSPC))
+	require.False(errors.Is(err, ErrPermission))
+
+	err = add(private)
+	require.True(errors.Is(err, ErrPermission))
+	var pathErr *os.PathError
+	require.True(errors.As(err, &pathErr))
+	require.Equal(private, pathErr.Path)
+
+	err = add(filepath.Join(rootDirectory, "missing"))
+	require.True(errors.Is(err, ErrRootNotFound))
+	require.True(os.IsNotExist(errors.Cause(errors.Unwrap(err))))
+
+	err = add(file)
+	require.True(errors.Is(err, ErrNotDirectory))
+
+	require.NoError(add(rootDirectory))
+
+	watcher.Stop()
+	require.True(errors.Is(add(rootDirectory), ErrWatcherStopped))
+}
@@ -0,0 +1,16 @@
+package dirwatch
+
+// OnExcluded calls fn with every path skipped because of an exclude
+// pattern, along with the pattern that matched, e.g. to validate an
+// exclude configuration. It is called for added roots, for the
+// sub-directories found while walking a recursive root or a Snapshot, and
+// for the paths of events. As AddRecursiveSync and AddAsync read directories
+// concurrently, fn may be called from several goroutines at once.
+func OnExcluded(fn func(path, pattern string)) Option {
+	return func(opt *options) {
+		opt.onExcluded = fn
+	}
+}
+
+// excludedByPattern prefixes the reason admit gives for excluded paths.
+const excludedByPattern = "excluded by pattern "
@@ -0,0 +1,50 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnExcluded(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-onexcluded")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	modules := filepath.Join(rootDirectory, "node_modules")
+	require.NoError(os.MkdirAll(filepath.Join(modules, "pkg"), 0777))
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "src"), 0777))
+
+	var (
+		mx       sync.Mutex
+		excluded = make(map[string]string)
+	)
+	watcher := New(
+		Notify(func(Event) {}),
+		Exclude("**/node_modules", "**/*.tmp"),
+		OnExcluded(func(path, pattern string) {
+			mx.Lock()
+			defer mx.Unlock()
+			excluded[path] = pattern
+		}))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+	tmp := filepath.Join(rootDirectory, "src", "build.tmp")
+	require.NoError(ioutil.WriteFile(tmp, []byte("DATA"), 0644))
+	<-time.After(time.Millisecond * 100)
+
+	mx.Lock()
+	defer mx.Unlock()
+	require.Equal(map[string]string{
+		modules: "**/node_modules",
+		tmp:     "**/*.tmp",
+	}, excluded)
+}
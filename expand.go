@@ -0,0 +1,61 @@
+package dirwatch
+
+import (
+	"os"
+	"sort"
+)
+
+// ExpandRemoves makes the Remove event of a watched directory be followed by
+// a synthetic Remove event for each of its descendants the watcher knows of,
+// every descendant before its parent directory, and drops their watches.
+// Sub-directories are always known; files are known only if the listing of
+// their directory is kept, which CountEntries does.
+func ExpandRemoves() Option {
+	return func(opt *options) {
+		opt.expandRemoves = true
+	}
+}
+
+// expandRemove emits the synthetic Remove events of ExpandRemoves for the
+// removed path name, and forgets its descendants. inf is the current info
+// of name, if it still exists.
+func (dw *Watcher) expandRemove(ev Event, name string, inf os.FileInfo) {
+	if !dw.expandRemoves || ev.Op&Remove == 0 || inf != nil {
+		return
+	}
+	if wp, ok := dw.paths[name]; !ok || !wp.dir {
+		return
+	}
+
+	var (
+		gone []string
+		dirs = make(map[string]bool)
+	)
+	for p, wp := range dw.paths {
+		if p != name && isUnder(p, name) {
+			gone = append(gone, p)
+			dirs[p] = wp.dir
+			dw.unregisterPath(p)
+		}
+	}
+	for dir, files := range dw.entries {
+		if !isUnder(dir, name) {
+			continue
+		}
+		for f := range files {
+			gone = append(gone, f)
+		}
+		delete(dw.entries, dir)
+	}
+	// a parent sorts before its descendants
+	sort.Sort(sort.Reverse(sort.StringSlice(gone)))
+
+	// the synthetic events go through the same filters as the real ones
+	now := dw.clock.Now()
+	for _, p := range gone {
+		if dw.excludePath(p) {
+			continue
+		}
+		dw.filterEvent(dw.attribute(Event{Name: p, Op: Remove, Time: now}), nil, dirs[p])
+	}
+}
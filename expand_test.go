@@ -0,0 +1,68 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandRemoves(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-expand")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	a := filepath.Join(rootDirectory, "a")
+	require.NoError(os.MkdirAll(filepath.Join(a, "b"), 0777))
+	files := []string{filepath.Join(a, "x.txt"), filepath.Join(a, "b", "y.txt")}
+	for _, name := range files {
+		require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+	}
+
+	// a fake backend, so that only the Remove of the directory itself is
+	// reported, as some platforms do.
+	fake := newFakeBackend()
+	events := make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		ExpandRemoves(),
+		OrderedDelivery(),
+		CountEntries(func(string, int) {}),
+		// the synthetic events are filtered like the real ones
+		ExcludeFunc(func(ev Event) bool { return ev.Op == Remove && filepath.Base(ev.Name) == "y.txt" }),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+	require.True(fake.watched(filepath.Join(a, "b")))
+
+	for _, name := range files {
+		fake.events <- fsnotify.Event{Name: name, Op: fsnotify.Write}
+	}
+	<-time.After(time.Millisecond * 100)
+	require.NoError(os.RemoveAll(a))
+	fake.events <- fsnotify.Event{Name: a, Op: fsnotify.Remove}
+
+	var removes []string
+T1:
+	for {
+		select {
+		case ev := <-events:
+			if ev.Op == Remove {
+				rel, err := filepath.Rel(rootDirectory, ev.Name)
+				require.NoError(err)
+				removes = append(removes, filepath.ToSlash(rel))
+			}
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal([]string{"a", "a/x.txt", "a/b"}, removes)
+}
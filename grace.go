@@ -0,0 +1,70 @@
+package dirwatch
+
+import "time"
+
+// StartupGrace drops every event for d after the watcher is created, so
+// the churn of a system that is still starting up, like other services
+// initializing or writing temporary files, does not trigger any
+// processing. With BufferStartupGrace, the events are held back instead,
+// and delivered once d has passed. Unlike SettleAfterAdd, it applies to
+// all roots, once.
+func StartupGrace(d time.Duration) Option {
+	return func(opt *options) {
+		opt.grace = d
+	}
+}
+
+// BufferStartupGrace makes StartupGrace hold back the events of the grace
+// period and deliver them, in order, once it is over, instead of dropping
+// them.
+func BufferStartupGrace() Option {
+	return func(opt *options) {
+		opt.graceBuffer = true
+	}
+}
+
+type graceWindow struct {
+	held []heldEvent
+}
+
+// startGrace opens the StartupGrace window.
+func (dw *Watcher) startGrace() {
+	if dw.grace <= 0 {
+		return
+	}
+	w := &graceWindow{}
+	dw.graceWindow = w
+	dw.clock.AfterFunc(dw.grace, func() {
+		dw.call(func(backend) { dw.endGrace(w) })
+	})
+}
+
+// dropGrace reports whether events fall in the StartupGrace window and are
+// dropped, not buffered. It runs on the agent goroutine.
+func (dw *Watcher) dropGrace() bool {
+	return dw.graceWindow != nil && !dw.graceBuffer
+}
+
+// holdGrace reports whether ev falls in the StartupGrace window, holding
+// it back. It runs on the agent goroutine.
+func (dw *Watcher) holdGrace(ev Event, isdir bool) bool {
+	w := dw.graceWindow
+	if w == nil {
+		return false
+	}
+	w.held = append(w.held, heldEvent{ev: ev, isdir: isdir})
+	return true
+}
+
+// endGrace closes the StartupGrace window w and delivers the events it
+// held. It runs on the agent goroutine.
+func (dw *Watcher) endGrace(w *graceWindow) {
+	if dw.graceWindow != w {
+		return
+	}
+	dw.graceWindow = nil
+	for _, h := range w.held {
+		dw.trace(h.ev.Name, "delivered", h.ev.Op)
+		dw.pass(h.ev, h.isdir)
+	}
+}
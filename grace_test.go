@@ -0,0 +1,120 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupGrace(t *testing.T) {
+	for _, buffer := range []bool{false, true} {
+		t.Run(map[bool]string{false: "drop", true: "buffer"}[buffer], func(t *testing.T) {
+			require := require.New(t)
+
+			rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-grace")
+			require.NoError(err)
+			defer os.RemoveAll(rootDirectory)
+			during := filepath.Join(rootDirectory, "during.txt")
+			after := filepath.Join(rootDirectory, "after.txt")
+			require.NoError(ioutil.WriteFile(during, []byte("DATA"), 0644))
+			require.NoError(ioutil.WriteFile(after, []byte("DATA"), 0644))
+
+			clock := newFakeClock()
+			fake := newFakeBackend()
+			var events = make(chan Event, 100)
+			opts := []Option{
+				Notify(func(ev Event) { events <- ev }),
+				StartupGrace(time.Minute),
+				withBackend(func() (backend, error) { return fake, nil }),
+				withClock(clock),
+			}
+			if buffer {
+				opts = append(opts, BufferStartupGrace())
+			}
+			watcher := New(opts...)
+			defer watcher.Stop()
+			clock.waitTimers(1)
+
+			fake.events <- fsnotify.Event{Name: during, Op: fsnotify.Write}
+			select {
+			case ev := <-events:
+				require.FailNow("delivered during the grace period", ev.Name)
+			case <-time.After(time.Millisecond * 300):
+			}
+
+			next := func() string {
+				select {
+				case ev := <-events:
+					return ev.Name
+				case <-time.After(time.Second * 5):
+					require.FailNow("no event")
+				}
+				return ""
+			}
+
+			// held events are not filtered, dropped ones are
+			watcher.call(func(backend) {})
+			require.Equal(map[bool]uint64{false: 1, true: 0}[buffer], watcher.Stats().Filtered)
+
+			clock.Advance(time.Minute)
+			if buffer {
+				require.Equal(during, next())
+			}
+			fake.events <- fsnotify.Event{Name: after, Op: fsnotify.Write}
+			require.Equal(after, next())
+		})
+	}
+}
+
+func TestStartupGraceDebounced(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-grace")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	during := filepath.Join(rootDirectory, "during.txt")
+	require.NoError(ioutil.WriteFile(during, []byte("DATA"), 0644))
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		StartupGrace(time.Minute),
+		BufferStartupGrace(),
+		PerFileDebounce(time.Second),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+	clock.waitTimers(1)
+
+	fake.events <- fsnotify.Event{Name: during, Op: fsnotify.Write}
+	fake.events <- fsnotify.Event{Name: during, Op: fsnotify.Write}
+	<-time.After(time.Millisecond * 100)
+
+	// the held events are debounced like any other
+	clock.Advance(time.Minute)
+	select {
+	case ev := <-events:
+		require.FailNow("held events were not debounced", ev.Name)
+	case <-time.After(time.Millisecond * 300):
+	}
+	clock.waitTimers(2)
+	clock.Advance(time.Second)
+	select {
+	case ev := <-events:
+		require.Equal(during, ev.Name)
+	case <-time.After(time.Second * 5):
+		require.FailNow("held events were not delivered")
+	}
+	select {
+	case ev := <-events:
+		require.FailNow("held events were delivered more than once", ev.Name)
+	case <-time.After(time.Millisecond * 300):
+	}
+}
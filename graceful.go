@@ -0,0 +1,138 @@
+package dirwatch
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StopGraceful stops the watcher, like Stop, once the events it has
+// already observed are delivered: it stops taking new events from the file
+// system, delivers the ones held back by StartupGrace, SettleAfterAdd,
+// TreatAsLeaf, PerFileDebounce, MinInterval and VerifyWithStat right away,
+// and waits for the queued and running notify callbacks to return. If that
+// takes longer than timeout, it stops anyway and returns an error.
+func (dw *Watcher) StopGraceful(timeout time.Duration) error {
+	defer dw.Stop()
+	if err := dw.call(func(watcher backend) { dw.drain(watcher) }); err != nil {
+		// already stopped
+		return nil
+	}
+	select {
+	case <-dw.undelivered.idle():
+		return nil
+	case <-dw.clock.After(timeout):
+		return errors.Errorf("graceful stop: %d events not delivered in %v", dw.undelivered.count(), timeout)
+	}
+}
+
+// Wait blocks until the watcher is stopped, by Stop or StopGraceful.
+func (dw *Watcher) Wait() {
+	<-dw.stopped()
+}
+
+// drain delivers the events the watcher already got, for StopGraceful, and
+// makes it ignore the ones to come. It runs on the agent goroutine.
+func (dw *Watcher) drain(watcher backend) {
+	dw.draining = true
+	for pending := true; pending; {
+		select {
+		case ev, ok := <-watcher.Events():
+			if !ok {
+				pending = false
+				continue
+			}
+			if dw.rawEvent != nil {
+				dw.rawEvent(ev)
+			}
+			dw.onEvent(Event{Name: ev.Name, Op: OpFromFsnotify(ev.Op), Time: dw.clock.Now()})
+		default:
+			pending = false
+		}
+	}
+
+	dw.releaseStale()
+	if dw.graceWindow != nil {
+		dw.endGrace(dw.graceWindow)
+	}
+	for root, w := range dw.settling {
+		dw.endSettle(root, w)
+	}
+
+	dw.leaves.mx.Lock()
+	var leaves []string
+	for leaf, t := range dw.leaves.timers {
+		t.Stop()
+		delete(dw.leaves.timers, leaf)
+		leaves = append(leaves, leaf)
+	}
+	dw.leaves.mx.Unlock()
+	for _, leaf := range leaves {
+		dw.deliver(dw.attribute(Event{Name: leaf, Op: Write, Time: dw.clock.Now()}))
+	}
+
+	// the evicted entries are flushed too, the events of a path in order
+	pending := dw.debouncing.take()
+	sort.Slice(pending, func(i, j int) bool { return pending[i].last.Time.Before(pending[j].last.Time) })
+	for _, d := range pending {
+		d.mx.Lock()
+		pending := !d.done
+		d.done = true
+		d.timer.Stop()
+		ev := d.last
+		d.mx.Unlock()
+		dw.state.remove(stateKey{stateDebounce, ev.Name})
+		if pending {
+			dw.deliver(ev)
+		}
+	}
+	dw.releaseSpaced()
+}
+
+// undelivered counts the events sent for delivery whose notify callbacks
+// have not returned yet.
+type undelivered struct {
+	mx      sync.Mutex
+	n       int
+	waiters []chan struct{}
+}
+
+func (u *undelivered) add() {
+	u.mx.Lock()
+	u.n++
+	u.mx.Unlock()
+}
+
+func (u *undelivered) done(n int) {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	u.n -= n
+	if u.n > 0 {
+		return
+	}
+	for _, c := range u.waiters {
+		close(c)
+	}
+	u.waiters = nil
+}
+
+func (u *undelivered) count() int {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	return u.n
+}
+
+// idle returns a channel that gets closed once no event is undelivered.
+func (u *undelivered) idle() <-chan struct{} {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	c := make(chan struct{})
+	if u.n <= 0 {
+		close(c)
+		return c
+	}
+	u.waiters = append(u.waiters, c)
+	return c
+}
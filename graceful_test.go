@@ -0,0 +1,134 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopGraceful(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-graceful")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	var names []string
+	for i := 0; i < 20; i++ {
+		fp := filepath.Join(rootDirectory, fmt.Sprintf("%d.txt", i))
+		require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+		names = append(names, fp)
+	}
+	debounced := filepath.Join(rootDirectory, "debounced.txt")
+	require.NoError(ioutil.WriteFile(debounced, []byte("DATA"), 0644))
+
+	fake := newFakeBackend()
+	var (
+		mx        sync.Mutex
+		delivered []string
+	)
+	watcher := New(
+		Notify(func(ev Event) {
+			time.Sleep(time.Millisecond * 20)
+			mx.Lock()
+			delivered = append(delivered, ev.Name)
+			mx.Unlock()
+		}),
+		OrderedDelivery(),
+		PerFileDebounce(time.Hour),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	fake.events <- fsnotify.Event{Name: debounced, Op: fsnotify.Write}
+	for _, fp := range names {
+		fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		watcher.Wait()
+		close(stopped)
+	}()
+	require.NoError(watcher.StopGraceful(time.Second * 10))
+	select {
+	case <-stopped:
+	case <-time.After(time.Second * 5):
+		require.FailNow("Wait did not return")
+	}
+
+	mx.Lock()
+	defer mx.Unlock()
+	require.ElementsMatch(append(names, debounced), delivered)
+
+	// stopping again is a no-op
+	require.NoError(watcher.StopGraceful(time.Second))
+}
+
+func TestStopGracefulEvicted(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-graceful")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	var names []string
+	for i := 0; i < 3; i++ {
+		fp := filepath.Join(rootDirectory, fmt.Sprintf("%d.txt", i))
+		require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+		names = append(names, fp)
+	}
+
+	fake := newFakeBackend()
+	var (
+		mx        sync.Mutex
+		delivered []string
+	)
+	watcher := New(
+		Notify(func(ev Event) {
+			mx.Lock()
+			delivered = append(delivered, ev.Name)
+			mx.Unlock()
+		}),
+		PerFileDebounce(time.Hour),
+		// every pending debounce but the last gets evicted
+		StateCapacity(1),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	for _, fp := range names {
+		fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	}
+	require.NoError(watcher.call(func(backend) {}))
+	require.Equal(1, watcher.Stats().State)
+
+	require.NoError(watcher.StopGraceful(time.Second * 10))
+	mx.Lock()
+	defer mx.Unlock()
+	require.ElementsMatch(names, delivered)
+}
+
+func TestStopGracefulTimeout(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-graceful")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fp := filepath.Join(rootDirectory, "slow.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+
+	fake := newFakeBackend()
+	release := make(chan struct{})
+	defer close(release)
+	watcher := New(
+		Notify(func(ev Event) { <-release }),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	require.Error(watcher.StopGraceful(time.Millisecond * 100))
+}
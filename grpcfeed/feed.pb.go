@@ -0,0 +1,224 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: feed.proto
+
+package grpcfeed
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// path_prefix, if set, limits the events to the ones under this path.
+	PathPrefix string `protobuf:"bytes,1,opt,name=path_prefix,json=pathPrefix,proto3" json:"path_prefix,omitempty"`
+	// ops, if not zero, limits the events to the ones with at least one of
+	// these dirwatch.Op bits.
+	Ops           uint32 `protobuf:"varint,2,opt,name=ops,proto3" json:"ops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_feed_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_feed_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_feed_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WatchRequest) GetPathPrefix() string {
+	if x != nil {
+		return x.PathPrefix
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetOps() uint32 {
+	if x != nil {
+		return x.Ops
+	}
+	return 0
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// op holds dirwatch.Op bits.
+	Op            uint32 `protobuf:"varint,2,opt,name=op,proto3" json:"op,omitempty"`
+	TimeUnixNano  int64  `protobuf:"varint,3,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	Root          string `protobuf:"bytes,4,opt,name=root,proto3" json:"root,omitempty"`
+	RelName       string `protobuf:"bytes,5,opt,name=rel_name,json=relName,proto3" json:"rel_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_feed_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_feed_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_feed_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Event) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Event) GetOp() uint32 {
+	if x != nil {
+		return x.Op
+	}
+	return 0
+}
+
+func (x *Event) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *Event) GetRoot() string {
+	if x != nil {
+		return x.Root
+	}
+	return ""
+}
+
+func (x *Event) GetRelName() string {
+	if x != nil {
+		return x.RelName
+	}
+	return ""
+}
+
+var File_feed_proto protoreflect.FileDescriptor
+
+const file_feed_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"feed.proto\x12\x10dirwatch.feed.v1\"A\n" +
+	"\fWatchRequest\x12\x1f\n" +
+	"\vpath_prefix\x18\x01 \x01(\tR\n" +
+	"pathPrefix\x12\x10\n" +
+	"\x03ops\x18\x02 \x01(\rR\x03ops\"\x80\x01\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x0e\n" +
+	"\x02op\x18\x02 \x01(\rR\x02op\x12$\n" +
+	"\x0etime_unix_nano\x18\x03 \x01(\x03R\ftimeUnixNano\x12\x12\n" +
+	"\x04root\x18\x04 \x01(\tR\x04root\x12\x19\n" +
+	"\brel_name\x18\x05 \x01(\tR\arelName2J\n" +
+	"\x04Feed\x12B\n" +
+	"\x05Watch\x12\x1e.dirwatch.feed.v1.WatchRequest\x1a\x17.dirwatch.feed.v1.Event0\x01B#Z!github.com/dc0d/dirwatch/grpcfeedb\x06proto3"
+
+var (
+	file_feed_proto_rawDescOnce sync.Once
+	file_feed_proto_rawDescData []byte
+)
+
+func file_feed_proto_rawDescGZIP() []byte {
+	file_feed_proto_rawDescOnce.Do(func() {
+		file_feed_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_feed_proto_rawDesc), len(file_feed_proto_rawDesc)))
+	})
+	return file_feed_proto_rawDescData
+}
+
+var file_feed_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_feed_proto_goTypes = []any{
+	(*WatchRequest)(nil), // 0: dirwatch.feed.v1.WatchRequest
+	(*Event)(nil),        // 1: dirwatch.feed.v1.Event
+}
+var file_feed_proto_depIdxs = []int32{
+	0, // 0: dirwatch.feed.v1.Feed.Watch:input_type -> dirwatch.feed.v1.WatchRequest
+	1, // 1: dirwatch.feed.v1.Feed.Watch:output_type -> dirwatch.feed.v1.Event
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_feed_proto_init() }
+func file_feed_proto_init() {
+	if File_feed_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_feed_proto_rawDesc), len(file_feed_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_feed_proto_goTypes,
+		DependencyIndexes: file_feed_proto_depIdxs,
+		MessageInfos:      file_feed_proto_msgTypes,
+	}.Build()
+	File_feed_proto = out.File
+	file_feed_proto_goTypes = nil
+	file_feed_proto_depIdxs = nil
+}
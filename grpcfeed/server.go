@@ -0,0 +1,82 @@
+// Package grpcfeed serves the events of a dirwatch.Watcher as a gRPC
+// stream, for consumers in other processes.
+//
+// feed.pb.go and feed_grpc.pb.go are generated from feed.proto.
+package grpcfeed
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dc0d/dirwatch"
+)
+
+// Server implements FeedServer on top of a watcher.
+type Server struct {
+	UnimplementedFeedServer
+
+	watcher *dirwatch.Watcher
+	buffer  int
+}
+
+// NewServer creates a Server streaming the events of watcher. Each stream
+// buffers up to buffer events; a client that falls further behind misses
+// events.
+func NewServer(watcher *dirwatch.Watcher, buffer int) *Server {
+	return &Server{watcher: watcher, buffer: buffer}
+}
+
+// Watch streams the events of the watcher that match req, until the client
+// goes away or the watcher stops.
+func (s *Server) Watch(req *WatchRequest, stream Feed_WatchServer) error {
+	events, cancel := s.watcher.Subscribe(s.buffer)
+	defer cancel()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if !matches(req, ev) {
+				continue
+			}
+			if err := stream.Send(toProto(ev)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func matches(req *WatchRequest, ev dirwatch.Event) bool {
+	if req.GetOps() != 0 && uint32(ev.Op)&req.GetOps() == 0 {
+		return false
+	}
+	prefix := req.GetPathPrefix()
+	return prefix == "" || isUnder(ev.Name, prefix)
+}
+
+// isUnder reports whether p is dir or a path under it; /a/bc is not under
+// /a/b.
+func isUnder(p, dir string) bool {
+	if p == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(p, dir)
+}
+
+func toProto(ev dirwatch.Event) *Event {
+	return &Event{
+		Name:         ev.Name,
+		Op:           uint32(ev.Op),
+		TimeUnixNano: ev.Time.UnixNano(),
+		Root:         ev.Root,
+		RelName:      ev.RelName,
+	}
+}
@@ -0,0 +1,65 @@
+package grpcfeed
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dc0d/dirwatch"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-grpcfeed")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "logs"), 0777))
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "data"), 0777))
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "database"), 0777))
+
+	watcher := dirwatch.New(dirwatch.Notify(func(dirwatch.Event) {}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterFeedServer(srv, NewServer(watcher, 100))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := NewFeedClient(conn).Watch(ctx, &WatchRequest{
+		PathPrefix: filepath.Join(rootDirectory, "data"),
+		Ops:        uint32(dirwatch.Create),
+	})
+	require.NoError(err)
+	<-time.After(time.Millisecond * 100)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "logs", "app.log"), []byte("DATA"), 0666))
+	// the prefix matches whole path elements
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "database", "db.log"), []byte("DATA"), 0666))
+	name := filepath.Join(rootDirectory, "data", "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+
+	ev, err := stream.Recv()
+	require.NoError(err)
+	require.Equal(name, ev.GetName())
+	require.Equal(uint32(dirwatch.Create), ev.GetOp()&uint32(dirwatch.Create))
+	require.Equal(rootDirectory, ev.GetRoot())
+}
@@ -0,0 +1,101 @@
+package dirwatch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"sync"
+)
+
+// ContentHashGate drops the Create and Write events of files whose content
+// has not changed since the last event of that file, e.g. when an editor
+// rewrites a file as it was. Files are hashed with SHA-256, off the agent
+// goroutine; files larger than maxSize bytes are not hashed and their
+// events always get through. The hashes are kept in the per-path state
+// bounded by StateCapacity.
+func ContentHashGate(maxSize int64) Option {
+	return func(opt *options) {
+		opt.hashMax = maxSize
+	}
+}
+
+// hashLocks serializes the work of ContentHashGate per path, as the events
+// of a path are inspected on concurrent goroutines.
+type hashLocks struct {
+	mx    sync.Mutex
+	paths map[string]*hashLock
+}
+
+type hashLock struct {
+	mx   sync.Mutex
+	refs int
+}
+
+// lock locks path, and returns the function that unlocks it.
+func (l *hashLocks) lock(path string) func() {
+	l.mx.Lock()
+	pl, ok := l.paths[path]
+	if !ok {
+		pl = &hashLock{}
+		l.paths[path] = pl
+	}
+	pl.refs++
+	l.mx.Unlock()
+
+	pl.mx.Lock()
+	return func() {
+		pl.mx.Unlock()
+		l.mx.Lock()
+		if pl.refs--; pl.refs == 0 {
+			delete(l.paths, path)
+		}
+		l.mx.Unlock()
+	}
+}
+
+// sameContent reports whether ev is dropped by ContentHashGate, recording
+// the current hash of its file. The hash of a path is compared and set by
+// one event at a time.
+func (dw *Watcher) sameContent(ev Event) bool {
+	if dw.hashMax <= 0 {
+		return false
+	}
+	name := dw.physical(ev)
+	defer dw.hashing.lock(name)()
+	key := stateKey{stateHash, name}
+	if ev.Op&(Remove|Rename) != 0 {
+		dw.state.remove(key)
+		return false
+	}
+	if ev.Op&(Create|Write) == 0 {
+		return false
+	}
+	sum, ok := hashFile(name, dw.hashMax)
+	if !ok {
+		dw.state.remove(key)
+		return false
+	}
+	last, seen := dw.state.get(key)
+	dw.state.set(key, sum)
+	return seen && bytes.Equal(last.([]byte), sum)
+}
+
+// hashFile returns the SHA-256 hash of the regular file at path, if it is
+// not larger than maxSize.
+func hashFile(path string, maxSize int64) ([]byte, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	inf, err := f.Stat()
+	if err != nil || !inf.Mode().IsRegular() || inf.Size() > maxSize {
+		return nil, false
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, maxSize+1)); err != nil {
+		return nil, false
+	}
+	return h.Sum(nil), true
+}
@@ -0,0 +1,97 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentHashGate(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-hash")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	name := filepath.Join(rootDirectory, "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), ContentHashGate(1<<20))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	// overwrite in place, so the file never has other content
+	overwrite := func(data string) {
+		f, err := os.OpenFile(name, os.O_WRONLY, 0666)
+		require.NoError(err)
+		_, err = f.WriteAt([]byte(data), 0)
+		require.NoError(err)
+		require.NoError(f.Close())
+		<-time.After(time.Millisecond * 100)
+	}
+	count := func() int {
+		var n int
+		for {
+			select {
+			case <-events:
+				n++
+			case <-time.After(time.Millisecond * 200):
+				return n
+			}
+		}
+	}
+
+	overwrite("DATA")
+	overwrite("DATA")
+	require.Equal(1, count())
+	stats := watcher.Stats()
+	require.Equal(uint64(1), stats.Delivered)
+	require.True(stats.Filtered > 0)
+
+	overwrite("MORE")
+	require.Equal(1, count())
+}
+
+func TestContentHashGateConcurrent(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-hash")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	name := filepath.Join(rootDirectory, "text.txt")
+	require.NoError(ioutil.WriteFile(name, make([]byte, 1<<20), 0666))
+
+	watcher := New(Notify(func(Event) {}), ContentHashGate(1<<20))
+	defer watcher.Stop()
+
+	// the events of one change, inspected at once, get through once
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		through int64
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if !watcher.sameContent(Event{Name: name, Op: Write}) {
+				atomic.AddInt64(&through, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	require.Equal(int64(1), through)
+	require.Equal(0, len(watcher.hashing.paths))
+}
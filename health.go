@@ -0,0 +1,53 @@
+package dirwatch
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HealthCheck verifies that every watched path still exists and is still
+// of the same kind (directory or file) as when it was registered. Problem
+// paths are dropped and listed in the returned error; the healthy ones are
+// registered again, which re-establishes watches that were invalidated
+// silently (e.g. after a remount); directories polled by PollOnWatchLimit
+// are only checked to exist. It runs on the agent goroutine and is
+// safe to call on a ticker.
+func (dw *Watcher) HealthCheck() error {
+	var problems []string
+	err := dw.call(func(watcher backend) {
+		for p, wp := range dw.paths {
+			isd, err := isDir(p)
+			switch {
+			case os.IsNotExist(err):
+				problems = append(problems, p+": no longer exists")
+			case err != nil:
+				problems = append(problems, p+": "+err.Error())
+			case wp.dir && !isd:
+				problems = append(problems, p+": no longer a directory")
+			case !wp.dir && isd:
+				problems = append(problems, p+": became a directory")
+			case dw.polled(p):
+				// polled for lack of watches, the stat above is the check
+				continue
+			default:
+				if err := watcher.Add(p); err != nil {
+					problems = append(problems, p+": "+err.Error())
+				}
+				continue
+			}
+			dw.unregisterPath(p)
+			watcher.Remove(p)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return errors.Errorf("health check failed: %s", strings.Join(problems, "; "))
+}
@@ -0,0 +1,47 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheck(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-health")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, false)
+	require.NoError(watcher.HealthCheck())
+
+	// a watched path that vanished without the agent noticing
+	missing := filepath.Join(rootDirectory, "missing")
+	require.NoError(watcher.call(func(backend) {
+		watcher.paths[missing] = watchedPath{dir: true}
+	}))
+
+	err = watcher.HealthCheck()
+	require.Error(err)
+	require.Contains(err.Error(), missing+": no longer exists")
+	require.NotContains(err.Error(), rootDirectory+":")
+
+	// the problem path is dropped and the root is still watched
+	require.NoError(watcher.HealthCheck())
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "text.txt"), nil, 0777))
+	select {
+	case ev := <-events:
+		require.Equal("text.txt", filepath.Base(ev.Name))
+	case <-time.After(time.Second * 5):
+		require.Fail("no event after health check")
+	}
+}
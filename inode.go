@@ -0,0 +1,39 @@
+package dirwatch
+
+import "os"
+
+// TrackInodes sets Event.Inode, to follow files through renames, like a log
+// tailer following app.log to app.log.1 as it gets rotated. The inode of a
+// file is recorded from its events, so the Rename event of its old name,
+// which can not be looked up anymore, reports the same inode as the Create
+// event of its new name. The inodes are kept in the state bounded by
+// StateCapacity. It is supported on Linux and macOS, where renaming within
+// a file system keeps the inode, and does nothing elsewhere, like on
+// Windows.
+func TrackInodes() Option {
+	return func(opt *options) {
+		opt.inodes = true
+	}
+}
+
+// markInode sets the Inode field of ev, as set by TrackInodes, with inf
+// being the current info of its file, if it exists. It runs on the agent
+// goroutine.
+func (dw *Watcher) markInode(ev Event, inf os.FileInfo) Event {
+	if !dw.inodes {
+		return ev
+	}
+	key := stateKey{stateInode, ev.Name}
+	if inf == nil {
+		if v, ok := dw.state.get(key); ok && ev.Op&(Remove|Rename) != 0 {
+			ev.Inode = v.(uint64)
+			dw.state.remove(key)
+		}
+		return ev
+	}
+	if ino, ok := fileInode(inf); ok {
+		ev.Inode = ino
+		dw.state.set(key, ino)
+	}
+	return ev
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package dirwatch
+
+import "os"
+
+func fileInode(os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,17 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package dirwatch
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileInode(inf os.FileInfo) (uint64, bool) {
+	st, ok := inf.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Ino), true
+}
@@ -0,0 +1,71 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackInodes(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-inodes")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	log := filepath.Join(rootDirectory, "app.log")
+	rotated := filepath.Join(rootDirectory, "app.log.1")
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		TrackInodes())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	time.Sleep(time.Millisecond * 100)
+
+	next := func(name string, op Op) Event {
+		for {
+			select {
+			case ev := <-events:
+				if ev.Name == name && ev.Op&op != 0 {
+					return ev
+				}
+			case <-time.After(time.Second * 5):
+				require.FailNow("no event", name)
+			}
+		}
+	}
+
+	require.NoError(ioutil.WriteFile(log, []byte("LINE"), 0644))
+	inode := next(log, Create).Inode
+	require.NotZero(inode)
+	time.Sleep(time.Millisecond * 100)
+
+	// both names report the inode, in whatever order they are delivered
+	require.NoError(os.Rename(log, rotated))
+	inodes := make(map[string]uint64)
+	for len(inodes) < 2 {
+		select {
+		case ev := <-events:
+			switch {
+			case ev.Name == log && ev.Op&Rename != 0:
+				inodes["old"] = ev.Inode
+			case ev.Name == rotated && ev.Op&Create != 0:
+				inodes["new"] = ev.Inode
+			}
+		case <-time.After(time.Second * 5):
+			require.FailNow("rename was not reported")
+		}
+	}
+	require.Equal(map[string]uint64{"old": inode, "new": inode}, inodes)
+
+	require.NoError(ioutil.WriteFile(log, []byte("LINE"), 0644))
+	require.NotEqual(inode, next(log, Create).Inode)
+}
@@ -0,0 +1,114 @@
+package dirwatch
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// RecordLatency makes the watcher record how long events take from being
+// observed (Event.Time) to being handed to the notify callback. See
+// Watcher.LatencyStats.
+func RecordLatency() Option {
+	return func(opt *options) {
+		opt.latency = true
+	}
+}
+
+// LatencyStats summarizes the recorded event latencies. Percentiles are
+// approximated by the upper bound of the histogram bucket they fall in.
+type LatencyStats struct {
+	Count uint64
+	Min   time.Duration
+	Max   time.Duration
+	P50   time.Duration
+	P99   time.Duration
+}
+
+// LatencyStats returns a summary of the event latencies recorded so far.
+// It is empty unless RecordLatency is used.
+func (dw *Watcher) LatencyStats() LatencyStats {
+	return dw.histogram.stats()
+}
+
+// latencyBuckets is the number of histogram buckets; the upper bound of
+// bucket i is 2^i microseconds, which covers up to about six days.
+const latencyBuckets = 40
+
+// histogram is a fixed-bucket, lock-free latency histogram.
+type histogram struct {
+	count   uint64
+	min     int64
+	max     int64
+	buckets [latencyBuckets]uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{min: math.MaxInt64}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := 0
+	for i < latencyBuckets-1 && d > bucketBound(i) {
+		i++
+	}
+	atomic.AddUint64(&h.buckets[i], 1)
+	for {
+		min := atomic.LoadInt64(&h.min)
+		if min <= int64(d) || atomic.CompareAndSwapInt64(&h.min, min, int64(d)) {
+			break
+		}
+	}
+	for {
+		max := atomic.LoadInt64(&h.max)
+		if max >= int64(d) || atomic.CompareAndSwapInt64(&h.max, max, int64(d)) {
+			break
+		}
+	}
+	atomic.AddUint64(&h.count, 1)
+}
+
+func (h *histogram) stats() LatencyStats {
+	res := LatencyStats{Count: atomic.LoadUint64(&h.count)}
+	if res.Count == 0 {
+		return res
+	}
+	res.Min = time.Duration(atomic.LoadInt64(&h.min))
+	res.Max = time.Duration(atomic.LoadInt64(&h.max))
+	var counts [latencyBuckets]uint64
+	var total uint64
+	for i := range counts {
+		counts[i] = atomic.LoadUint64(&h.buckets[i])
+		total += counts[i]
+	}
+	res.P50 = h.percentile(counts[:], total, 50, res)
+	res.P99 = h.percentile(counts[:], total, 99, res)
+	return res
+}
+
+func (h *histogram) percentile(counts []uint64, total uint64, p uint64, res LatencyStats) time.Duration {
+	rank := (total*p + 99) / 100
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen < rank {
+			continue
+		}
+		d := bucketBound(i)
+		if d > res.Max {
+			d = res.Max
+		}
+		if d < res.Min {
+			d = res.Min
+		}
+		return d
+	}
+	return res.Max
+}
+
+func bucketBound(i int) time.Duration {
+	return time.Microsecond << uint(i)
+}
@@ -0,0 +1,63 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	require := require.New(t)
+
+	h := newHistogram()
+	require.Equal(LatencyStats{}, h.stats())
+
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	st := h.stats()
+	require.Equal(uint64(100), st.Count)
+	require.Equal(time.Millisecond, st.Min)
+	require.Equal(100*time.Millisecond, st.Max)
+	// 50ms falls in the (32.768ms, 65.536ms] bucket
+	require.Equal(bucketBound(16), st.P50)
+	// 99ms falls in the last used bucket, capped by the max
+	require.Equal(100*time.Millisecond, st.P99)
+}
+
+func TestRecordLatency(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-latency")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), RecordLatency())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, fmt.Sprintf("%d.txt", i)), nil, 0777))
+	}
+	for i := 0; i < 5; i++ {
+		select {
+		case ev := <-events:
+			require.False(ev.Time.IsZero())
+		case <-time.After(time.Second * 5):
+			require.FailNow("missing events")
+		}
+	}
+
+	st := watcher.LatencyStats()
+	require.True(st.Count >= 5)
+	require.True(st.Min <= st.P50)
+	require.True(st.P50 <= st.P99)
+	require.True(st.P99 <= st.Max)
+}
@@ -0,0 +1,93 @@
+package dirwatch
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TreatAsLeaf makes the directories whose base names match one of patterns,
+// with the syntax of Exclude, be reported as single units, e.g. macOS app
+// bundles with *.app. The events of the paths inside such a directory are
+// not delivered; instead, once they have stopped for 100ms, one Write event
+// is delivered for the directory itself. A recursive add watches the leaf
+// directory, but does not walk into it, so only the changes of its own
+// entries are seen.
+func TreatAsLeaf(patterns ...string) Option {
+	return func(opt *options) {
+		opt.leafPatterns = append(opt.leafPatterns, patterns...)
+	}
+}
+
+// leafQuiet is how long the changes inside a leaf directory have to stop
+// before its event is delivered.
+const leafQuiet = time.Millisecond * 100
+
+type leafDirs struct {
+	mx     sync.Mutex
+	timers map[string]timer
+}
+
+// leafOf returns the outermost ancestor of name, below its root, that is a
+// leaf directory.
+func (dw *Watcher) leafOf(name string) (string, bool) {
+	var found string
+	for dir := filepath.Dir(name); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, ok := dw.roots[dir]; ok {
+			break
+		}
+		if dw.isLeaf(dir) {
+			found = dir
+		}
+	}
+	return found, found != ""
+}
+
+// isLeaf reports whether the base name of dir matches a pattern of
+// TreatAsLeaf.
+func (dw *Watcher) isLeaf(dir string) bool {
+	base := filepath.Base(dir)
+	for _, ptrn := range dw.leafPatterns {
+		// the patterns are validated on construction
+		if ok, _ := matchPattern(ptrn, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// foldIntoLeaf reports whether ev is inside a leaf directory, and if so,
+// (re)schedules the event of that directory. It runs on the agent
+// goroutine.
+func (dw *Watcher) foldIntoLeaf(ev Event) bool {
+	if len(dw.leafPatterns) == 0 {
+		return false
+	}
+	leaf, ok := dw.leafOf(ev.Name)
+	if !ok {
+		return false
+	}
+	dw.trace(ev.Name, "folded into leaf", leaf)
+	dw.leaves.mx.Lock()
+	defer dw.leaves.mx.Unlock()
+	if t, ok := dw.leaves.timers[leaf]; ok {
+		t.Stop()
+	}
+	var t timer
+	t = dw.clock.AfterFunc(leafQuiet, func() {
+		dw.leaves.mx.Lock()
+		current := dw.leaves.timers[leaf] == t
+		if current {
+			delete(dw.leaves.timers, leaf)
+		}
+		dw.leaves.mx.Unlock()
+		if !current {
+			return
+		}
+		dw.call(func(backend) {
+			dw.deliver(dw.attribute(Event{Name: leaf, Op: Write, Time: dw.clock.Now()}))
+		})
+	})
+	dw.leaves.timers[leaf] = t
+	return true
+}
@@ -0,0 +1,65 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreatAsLeaf(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-leaf")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	app := filepath.Join(rootDirectory, "Editor.app")
+	contents := filepath.Join(app, "Contents")
+	require.NoError(os.MkdirAll(filepath.Join(contents, "MacOS"), 0777))
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), TreatAsLeaf("*.app"))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	// the walk stops at the bundle
+	var watched []string
+	require.NoError(watcher.call(func(backend) {
+		for p := range watcher.paths {
+			watched = append(watched, p)
+		}
+	}))
+	require.ElementsMatch([]string{rootDirectory, app}, watched)
+
+	// an update of the bundle
+	require.NoError(ioutil.WriteFile(filepath.Join(app, "PkgInfo"), []byte("DATA"), 0644))
+	require.NoError(os.Mkdir(filepath.Join(app, "Resources"), 0777))
+	require.NoError(ioutil.WriteFile(filepath.Join(contents, "Info.plist"), []byte("DATA"), 0644))
+	other := filepath.Join(rootDirectory, "readme.txt")
+	require.NoError(ioutil.WriteFile(other, []byte("DATA"), 0644))
+
+	var got []Event
+T1:
+	for {
+		select {
+		case ev := <-events:
+			got = append(got, ev)
+		case <-time.After(time.Millisecond * 400):
+			break T1
+		}
+	}
+	var leafEvents int
+	for _, ev := range got {
+		if ev.Name == other {
+			continue
+		}
+		require.Equal(app, ev.Name)
+		require.Equal(Write, ev.Op)
+		leafEvents++
+	}
+	require.Equal(1, leafEvents)
+}
@@ -0,0 +1,68 @@
+package dirwatch
+
+import "sync"
+
+// WatcherState is the lifecycle state of a watcher, as reported by
+// OnStateChange.
+type WatcherState int
+
+// The states of a watcher.
+const (
+	// Starting is the state of a watcher until its first start completes.
+	Starting WatcherState = iota
+	// Running is the state of a watcher that watches as expected.
+	Running
+	// Degraded is the state of a watcher whose backend failed; it is
+	// restarted after a second.
+	Degraded
+	// Recovering is the state of a degraded watcher that is restarting.
+	Recovering
+	// Stopped is the final state of a watcher, after Stop.
+	Stopped
+)
+
+var stateNames = []string{"STARTING", "RUNNING", "DEGRADED", "RECOVERING", "STOPPED"}
+
+func (s WatcherState) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return "UNKNOWN"
+	}
+	return stateNames[s]
+}
+
+// OnStateChange calls fn every time the watcher moves to another state,
+// e.g. to Degraded when its backend fails and back to Running once it is
+// re-established. The calls are made in order, one at a time, so fn
+// should not block.
+func OnStateChange(fn func(state WatcherState)) Option {
+	return func(opt *options) {
+		opt.stateChange = fn
+	}
+}
+
+type lifecycle struct {
+	mx       sync.Mutex
+	current  WatcherState
+	reported bool
+}
+
+// setState moves the watcher to state s. Once stopped, a watcher stays
+// stopped.
+func (dw *Watcher) setState(s WatcherState) {
+	dw.lifecycle.mx.Lock()
+	defer dw.lifecycle.mx.Unlock()
+	if dw.lifecycle.reported && (dw.lifecycle.current == s || dw.lifecycle.current == Stopped) {
+		return
+	}
+	dw.lifecycle.reported = true
+	dw.lifecycle.current = s
+	if dw.stateChange != nil {
+		dw.stateChange(s)
+	}
+}
+
+func (dw *Watcher) currentState() WatcherState {
+	dw.lifecycle.mx.Lock()
+	defer dw.lifecycle.mx.Unlock()
+	return dw.lifecycle.current
+}
@@ -0,0 +1,55 @@
+package dirwatch
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnStateChange(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		mx       sync.Mutex
+		backends []*fakeBackend
+	)
+	newBackend := func() (backend, error) {
+		mx.Lock()
+		defer mx.Unlock()
+		fake := newFakeBackend()
+		backends = append(backends, fake)
+		return fake, nil
+	}
+	var states = make(chan WatcherState, 100)
+	watcher := New(
+		Notify(func(Event) {}),
+		withBackend(newBackend),
+		OnStateChange(func(state WatcherState) { states <- state }))
+	defer watcher.Stop()
+
+	next := func() WatcherState {
+		select {
+		case s := <-states:
+			return s
+		case <-time.After(time.Second * 5):
+			require.FailNow("no state change")
+		}
+		return 0
+	}
+	require.Equal(Starting, next())
+	require.Equal(Running, next())
+
+	// the backend fails, the agent is restarted
+	mx.Lock()
+	close(backends[0].events)
+	mx.Unlock()
+	require.Equal(Degraded, next())
+	require.Equal(Recovering, next())
+	require.Equal(Running, next())
+
+	watcher.Stop()
+	require.Equal(Stopped, next())
+	require.Equal("STOPPED", Stopped.String())
+}
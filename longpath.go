@@ -0,0 +1,19 @@
+package dirwatch
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// tooLong marks err with the offending path if it is caused by path
+// exceeding the length limits of the OS (ENAMETOOLONG), so it stands out on
+// the errors channel. Such a path is skipped, along with its sub-tree, while
+// the rest of the tree is still watched. On Windows, long paths are handled
+// by the os package itself, which uses the \\?\ prefix where needed.
+func tooLong(err error, path string) error {
+	if err == nil || !errors.Is(err, syscall.ENAMETOOLONG) {
+		return err
+	}
+	return errors.Wrapf(err, "path too long (%d bytes), skipped: %s", len(path), path)
+}
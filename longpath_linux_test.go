@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestLongPaths(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-long")
+	require.NoError(err)
+	defer func() {
+		// os.RemoveAll works relative to open directories, so it copes
+		// with the long paths.
+		require.NoError(os.RemoveAll(rootDirectory))
+	}()
+
+	// nest directories relative to their parents, until the full path is
+	// over PATH_MAX.
+	deep := filepath.Join(rootDirectory, "deep")
+	require.NoError(os.Mkdir(deep, 0777))
+	fd, err := unix.Open(deep, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	require.NoError(err)
+	name := strings.Repeat("d", 200)
+	path := deep
+	for len(path) <= unix.PathMax {
+		require.NoError(unix.Mkdirat(fd, name, 0777))
+		next, err := unix.Openat(fd, name, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+		require.NoError(err)
+		unix.Close(fd)
+		fd = next
+		path = filepath.Join(path, name)
+	}
+	unix.Close(fd)
+	sibling := filepath.Join(rootDirectory, "sibling")
+	require.NoError(os.Mkdir(sibling, 0777))
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), Logger(func(...interface{}) {}))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+
+	select {
+	case err := <-watcher.Errors():
+		require.Contains(err.Error(), "path too long")
+	case <-time.After(time.Second * 2):
+		require.Fail("long path not reported")
+	}
+	// the walk may run ahead of registering the directories it found
+	<-time.After(time.Millisecond * 100)
+
+	name = filepath.Join(sibling, "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+	select {
+	case ev := <-events:
+		require.Equal(name, ev.Name)
+	case <-time.After(time.Second):
+		require.Fail("sibling not watched")
+	}
+}
@@ -0,0 +1,103 @@
+package dirwatch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares the roots to watch and the patterns to exclude, for
+// NewFromManifest. In YAML:
+//
+//	roots:
+//	  - path: /srv/data
+//	    recursive: true
+//	  - path: ./config
+//	exclude:
+//	  - "**/.git"
+//	  - /srv/data/tmp
+//
+// Relative root paths are relative to the directory of the manifest file.
+type Manifest struct {
+	Roots   []ManifestRoot `yaml:"roots"`
+	Exclude []string       `yaml:"exclude"`
+}
+
+// ManifestRoot is a root declared in a Manifest.
+type ManifestRoot struct {
+	Path      string `yaml:"path"`
+	Recursive bool   `yaml:"recursive"`
+}
+
+// NewFromManifest creates a new *Watcher configured by the YAML manifest
+// file at path, and adds all of its roots. An invalid manifest, e.g. with a
+// bad pattern or a root that does not exist, is reported as an error and no
+// watcher is created. Further options are applied after the ones of the
+// manifest.
+func NewFromManifest(path string, notify func(Event), opt ...Option) (*Watcher, error) {
+	manifest, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]Option{Notify(notify), Exclude(manifest.Exclude...)}, opt...)
+	dw, err := NewWithError(opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range manifest.Roots {
+		if err := dw.AddContext(context.Background(), root.Path, root.Recursive); err != nil {
+			dw.Stop()
+			return nil, errors.Wrapf(err, "manifest %s: root %s", path, root.Path)
+		}
+	}
+	return dw, nil
+}
+
+// readManifest parses and validates the manifest file at path, making its
+// root paths absolute.
+func readManifest(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var manifest Manifest
+	if err := dec.Decode(&manifest); err != nil {
+		return nil, errors.Wrapf(err, "manifest %s", path)
+	}
+
+	var problems []string
+	if len(manifest.Roots) == 0 {
+		problems = append(problems, "no roots declared")
+	}
+	dir := filepath.Dir(path)
+	for i, root := range manifest.Roots {
+		if root.Path == "" {
+			problems = append(problems, "root with no path")
+			continue
+		}
+		if !filepath.IsAbs(root.Path) {
+			root.Path = filepath.Join(dir, root.Path)
+		}
+		if _, err := os.Stat(root.Path); err != nil {
+			problems = append(problems, "root "+err.Error())
+		}
+		manifest.Roots[i].Path = root.Path
+	}
+	for _, ptrn := range manifest.Exclude {
+		if err := validatePattern(ptrn); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.Errorf("manifest %s: %s", path, strings.Join(problems, "; "))
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,78 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromManifest(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-manifest")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "data", "sub"), 0777))
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "data", ".git"), 0777))
+
+	manifest := filepath.Join(rootDirectory, "dirwatch.yaml")
+	require.NoError(ioutil.WriteFile(manifest, []byte(`
+roots:
+  - path: data
+    recursive: true
+exclude:
+  - "**/.git"
+`), 0666))
+
+	events := make(chan Event, 100)
+	watcher, err := NewFromManifest(manifest, func(ev Event) { events <- ev })
+	require.NoError(err)
+	defer watcher.Stop()
+	<-time.After(time.Millisecond * 50)
+
+	name := filepath.Join(rootDirectory, "data", "sub", "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "data", ".git", "HEAD"), []byte("DATA"), 0666))
+
+	var names []string
+T1:
+	for {
+		select {
+		case ev := <-events:
+			names = append(names, ev.Name)
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Contains(names, name)
+	require.NotContains(names, filepath.Join(rootDirectory, "data", ".git", "HEAD"))
+}
+
+func TestNewFromManifestInvalid(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-manifest")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	manifest := filepath.Join(rootDirectory, "dirwatch.yaml")
+	require.NoError(ioutil.WriteFile(manifest, []byte(`
+roots:
+  - path: missing
+exclude:
+  - "[bad"
+`), 0666))
+
+	_, err = NewFromManifest(manifest, func(Event) {})
+	require.Error(err)
+	require.Contains(err.Error(), "missing")
+	require.Contains(err.Error(), "[bad")
+
+	require.NoError(ioutil.WriteFile(manifest, []byte("roots:\n  - paht: data\n"), 0666))
+	_, err = NewFromManifest(manifest, func(Event) {})
+	require.Error(err)
+}
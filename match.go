@@ -0,0 +1,65 @@
+package dirwatch
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Exclude patterns are matched the same way on every platform: the pattern
+// and the path are both put in slash form (see filepath.ToSlash), then
+// matched using path.Match. So * and ? never match a separator, and \
+// escapes the next character, except on Windows, where it is a separator;
+// a character class like [*] works everywhere. A pattern starting with **/
+// is matched against the base name of the path, so the rest of it can not
+// hold a separator.
+
+var errEmptyPattern = errors.New("empty pattern")
+
+// matchAny reports whether p matches any of patterns.
+func matchAny(patterns []string, p string) (bool, error) {
+	_, ok, err := firstMatch(patterns, p)
+	return ok, err
+}
+
+// firstMatch returns the first of patterns that p matches.
+func firstMatch(patterns []string, p string) (string, bool, error) {
+	p = filepath.ToSlash(p)
+	for _, ptrn := range patterns {
+		ok, err := matchPattern(ptrn, p)
+		if err != nil {
+			return "", false, errors.Wrapf(err, "exclude pattern %q", ptrn)
+		}
+		if ok {
+			return ptrn, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// matchPattern reports whether p, in slash form, matches ptrn.
+func matchPattern(ptrn, p string) (bool, error) {
+	ptrn = filepath.ToSlash(ptrn)
+	if strings.HasPrefix(ptrn, anyDepth) {
+		ptrn = ptrn[len(anyDepth):]
+		if strings.Contains(ptrn, "/") {
+			return false, errors.Errorf("%s matches base names, which have no separator", anyDepth)
+		}
+		p = path.Base(p)
+	}
+	if ptrn == "" {
+		return false, errEmptyPattern
+	}
+	return path.Match(ptrn, p)
+}
+
+// validatePattern returns an error if ptrn is malformed; matching a valid
+// pattern never fails.
+func validatePattern(ptrn string) error {
+	if _, err := matchPattern(ptrn, ""); err != nil {
+		return errors.Wrapf(err, "exclude pattern %q", ptrn)
+	}
+	return nil
+}
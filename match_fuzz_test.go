@@ -0,0 +1,43 @@
+//go:build go1.18
+// +build go1.18
+
+package dirwatch
+
+import (
+	"path"
+	"testing"
+)
+
+func FuzzMatchAny(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"/data/*.tmp", "/data/a.tmp"},
+		{"**/.git", "/repo/.git"},
+		{"/data/[abc]", "/data/b"},
+		{"/data/[^a-c]", "/data/d"},
+		{"/data/\\*", "/data/*"},
+		{"[", "/data"},
+		{"**/", "/"},
+		{"", ""},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, ptrn, p string) {
+		matched, err := matchAny([]string{ptrn}, p)
+		if verr := validatePattern(ptrn); verr != nil {
+			if err == nil && matched {
+				t.Fatalf("invalid pattern %q matched %q", ptrn, p)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("valid pattern %q failed on %q: %v", ptrn, p, err)
+		}
+		// a base name pattern does not depend on the directory
+		if len(ptrn) > len(anyDepth) && ptrn[:len(anyDepth)] == anyDepth {
+			again, _ := matchAny([]string{ptrn}, path.Join("/other/dir", path.Base(p)))
+			if again != matched && path.Base(p) != "/" && path.Base(p) != "." {
+				t.Fatalf("pattern %q matched %q but not the same base name elsewhere", ptrn, p)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package dirwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchAny(t *testing.T) {
+	require := require.New(t)
+
+	cases := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/data/*.tmp", "/data/a.tmp", true},
+		{"/data/*.tmp", "/data/sub/a.tmp", false},
+		{"/data/?", "/data/a", true},
+		{"/data/?", "/data/ab", false},
+		{"/*/*/node_modules", "/home/u/node_modules", true},
+		{"/*/*/node_modules", "/home/u/x/node_modules", false},
+		{"**/.git", "/repo/.git", true},
+		{"**/.git", "/repo/sub/.git", true},
+		{"**/.git", "/repo/.github", false},
+		{"**/*.tmp", "/a/b/c.tmp", true},
+		{"**/*.tmp", "/a/b.tmp/c", false},
+		{"/data/[abc].txt", "/data/b.txt", true},
+		{"/data/[abc].txt", "/data/d.txt", false},
+		{"/data/[^abc].txt", "/data/d.txt", true},
+		{"/data/[a-c]*", "/data/cat", true},
+		{"/data/[*]", "/data/*", true},
+		{"/data/[*]", "/data/x", false},
+		{"/data/[[]x]", "/data/[x]", true},
+		{"/data/[?]", "/data/a", false},
+		{"/data", "/data/", false},
+	}
+	for _, c := range cases {
+		matched, err := matchAny([]string{c.pattern}, c.path)
+		require.NoError(err, c.pattern)
+		require.Equal(c.matched, matched, "%s %s", c.pattern, c.path)
+	}
+
+	matched, err := matchAny(nil, "/data")
+	require.NoError(err)
+	require.False(matched)
+
+	matched, err = matchAny([]string{"/other", "**/data"}, "/data")
+	require.NoError(err)
+	require.True(matched)
+}
+
+func TestValidatePattern(t *testing.T) {
+	require := require.New(t)
+
+	for _, ptrn := range []string{
+		"",
+		"**/",
+		"[",
+		"/data/[abc",
+		"/data/[]",
+		"/data/[z-a",
+		"**/sub/.git",
+		"**/[",
+	} {
+		require.Error(validatePattern(ptrn), ptrn)
+	}
+	for _, ptrn := range []string{"/data", "**/.git", "/data/[[]", "/data/*/x"} {
+		require.NoError(validatePattern(ptrn), ptrn)
+	}
+
+	_, err := NewWithError(Notify(func(Event) {}), Exclude("**/.git", "/data/[abc"))
+	require.Error(err)
+	require.Contains(err.Error(), "/data/[abc")
+	require.Panics(func() { New(Notify(func(Event) {}), Exclude("[")) })
+}
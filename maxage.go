@@ -0,0 +1,27 @@
+package dirwatch
+
+import (
+	"os"
+	"time"
+)
+
+// MaxFileAge drops the events of files last modified more than d ago, e.g.
+// to ignore the historical files a directory was seeded with while still
+// reporting new arrivals. A write updates the modification time, so the
+// files that change keep being reported. With ScanExisting, existing files
+// older than d are not reported either. Directories and removed files are
+// not affected.
+func MaxFileAge(d time.Duration) Option {
+	return func(opt *options) {
+		opt.maxAge = d
+	}
+}
+
+// tooOld reports whether the file described by inf is dropped by
+// MaxFileAge.
+func (dw *Watcher) tooOld(inf os.FileInfo) bool {
+	if dw.maxAge <= 0 || inf == nil || inf.IsDir() {
+		return false
+	}
+	return dw.clock.Now().Sub(inf.ModTime()) > dw.maxAge
+}
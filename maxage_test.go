@@ -0,0 +1,54 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxFileAge(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-maxage")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	old := filepath.Join(rootDirectory, "old.log")
+	require.NoError(ioutil.WriteFile(old, []byte("DATA"), 0644))
+	past := time.Now().Add(-time.Hour * 48)
+	require.NoError(os.Chtimes(old, past, past))
+	existing := filepath.Join(rootDirectory, "existing.log")
+	require.NoError(ioutil.WriteFile(existing, []byte("DATA"), 0644))
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		MaxFileAge(time.Hour*24),
+		ScanExisting())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	// a metadata change keeps the modification time
+	require.NoError(os.Chmod(old, 0600))
+	fresh := filepath.Join(rootDirectory, "fresh.log")
+	require.NoError(ioutil.WriteFile(fresh, []byte("DATA"), 0644))
+
+	seen := make(map[string]bool)
+T1:
+	for {
+		select {
+		case ev := <-events:
+			seen[ev.Name] = true
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.True(seen[existing])
+	require.True(seen[fresh])
+	require.False(seen[old])
+}
@@ -0,0 +1,26 @@
+package dirwatch
+
+// Use adds middleware that every event goes through, in order, before it
+// is delivered. Each one gets the event returned by the previous one and
+// can change it, e.g. to enrich it or rewrite its path, or drop it by
+// returning false. Middleware runs on the watcher goroutine, in the order
+// the events were observed, so it should be quick. ContentHashGate reads
+// the file at the Name middleware returns.
+func Use(middleware ...func(Event) (Event, bool)) Option {
+	return func(opt *options) {
+		opt.middleware = append(opt.middleware, middleware...)
+	}
+}
+
+// runMiddleware passes ev through the middleware set by Use, and reports
+// whether it is to be delivered.
+func (dw *Watcher) runMiddleware(ev Event) (Event, bool) {
+	for _, mw := range dw.middleware {
+		var ok bool
+		ev, ok = mw(ev)
+		if !ok {
+			return ev, false
+		}
+	}
+	return ev, true
+}
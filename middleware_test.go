@@ -0,0 +1,53 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUse(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-middleware")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	rewrite := func(ev Event) (Event, bool) {
+		ev.Name = strings.TrimPrefix(ev.Name, rootDirectory+string(filepath.Separator))
+		return ev, true
+	}
+	dropLogs := func(ev Event) (Event, bool) {
+		// runs after rewrite, so it sees the relative name
+		return ev, !strings.HasPrefix(ev.Name, "logs")
+	}
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		Use(rewrite, dropLogs),
+		OrderedDelivery())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "logs-1.txt"), []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "main.go"), []byte("DATA"), 0644))
+
+	names := make(map[string]bool)
+T1:
+	for {
+		select {
+		case ev := <-events:
+			names[ev.Name] = true
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(map[string]bool{"main.go": true}, names)
+}
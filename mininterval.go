@@ -0,0 +1,69 @@
+package dirwatch
+
+import (
+	"sync"
+	"time"
+)
+
+// MinInterval delivers at most one event every d, for consumers that only
+// care that something changed, like a reindex that must not run more often
+// than every d no matter how much changes. The first event after a quiet d
+// is delivered right away; the ones that follow within d are coalesced into
+// the last of them, delivered once d has passed since the one before, with
+// Coalesced set to the number of events it stands for. Unlike a debounce,
+// it keeps delivering every d while changes go on. Heartbeats are not
+// limited.
+func MinInterval(d time.Duration) Option {
+	return func(opt *options) {
+		opt.minInterval = d
+	}
+}
+
+type spacing struct {
+	mx    sync.Mutex
+	last  time.Time
+	held  *Event
+	count int
+}
+
+// spaceOut reports whether ev can be delivered now, as limited by
+// MinInterval, holding it back otherwise.
+func (dw *Watcher) spaceOut(ev Event) (Event, bool) {
+	if dw.minInterval <= 0 || ev.Op == Beat {
+		return ev, true
+	}
+	s := &dw.spacing
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	now := dw.clock.Now()
+	if s.held == nil && (s.last.IsZero() || now.Sub(s.last) >= dw.minInterval) {
+		s.last = now
+		ev.Coalesced = 1
+		return ev, true
+	}
+	if s.held == nil {
+		dw.clock.AfterFunc(s.last.Add(dw.minInterval).Sub(now), func() {
+			dw.call(func(backend) { dw.releaseSpaced() })
+		})
+	}
+	s.held = &ev
+	s.count++
+	return ev, false
+}
+
+// releaseSpaced delivers the event held back by MinInterval, if any. It
+// runs on the agent goroutine.
+func (dw *Watcher) releaseSpaced() {
+	s := &dw.spacing
+	s.mx.Lock()
+	if s.held == nil {
+		s.mx.Unlock()
+		return
+	}
+	ev := *s.held
+	ev.Coalesced = s.count
+	s.held, s.count = nil, 0
+	s.last = dw.clock.Now()
+	s.mx.Unlock()
+	dw.send(dw.persist(ev))
+}
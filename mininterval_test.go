@@ -0,0 +1,57 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinInterval(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-mininterval")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	const interval = time.Millisecond * 200
+	var (
+		mx        sync.Mutex
+		times     []time.Time
+		coalesced int
+	)
+	watcher := New(
+		Notify(func(ev Event) {
+			mx.Lock()
+			defer mx.Unlock()
+			times = append(times, time.Now())
+			coalesced += ev.Coalesced
+		}),
+		MinInterval(interval))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 100)
+
+	// writes keep coming, never leaving a quiet interval
+	writes := 0
+	for start := time.Now(); time.Since(start) < interval*6; writes++ {
+		fp := filepath.Join(rootDirectory, fmt.Sprintf("file%d.txt", writes))
+		require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+		<-time.After(time.Millisecond * 10)
+	}
+	<-time.After(interval * 2)
+
+	mx.Lock()
+	defer mx.Unlock()
+	require.True(len(times) >= 5 && len(times) <= 8, "%d notifications", len(times))
+	for i := 1; i < len(times); i++ {
+		// notify runs on goroutines of its own, hence the slack
+		require.True(times[i].Sub(times[i-1]) > interval/2, "notified after %v", times[i].Sub(times[i-1]))
+	}
+	require.True(coalesced >= writes, "%d events for %d writes", coalesced, writes)
+}
@@ -0,0 +1,80 @@
+package dirwatch
+
+import (
+	"sort"
+	"sync"
+)
+
+// MultiWatcher merges the events of several watchers, each with its own
+// options, into one stream. Every event carries the name of the watcher
+// that reported it in Source.
+type MultiWatcher struct {
+	watchers map[string]*Watcher
+	events   chan Event
+	done     chan struct{}
+	stop     sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewMultiWatcher merges the events of watchers, keyed by their names. It
+// subscribes to each watcher with a buffer of buffer events; like
+// Subscribe, a watcher whose buffer is full misses events, so Events
+// should be drained promptly. The watchers still notify their own Notify
+// functions.
+func NewMultiWatcher(watchers map[string]*Watcher, buffer int) *MultiWatcher {
+	res := &MultiWatcher{
+		watchers: make(map[string]*Watcher, len(watchers)),
+		events:   make(chan Event, buffer),
+		done:     make(chan struct{}),
+	}
+	for name, w := range watchers {
+		res.watchers[name] = w
+		events, _ := w.Subscribe(buffer)
+		res.wg.Add(1)
+		go res.forward(name, events)
+	}
+	go func() {
+		res.wg.Wait()
+		close(res.events)
+	}()
+	return res
+}
+
+func (mw *MultiWatcher) forward(name string, events <-chan Event) {
+	defer mw.wg.Done()
+	for ev := range events {
+		ev.Source = name
+		select {
+		case mw.events <- ev:
+		case <-mw.done:
+			return
+		}
+	}
+}
+
+// Events returns the merged events. The channel is closed once every
+// watcher has stopped.
+func (mw *MultiWatcher) Events() <-chan Event { return mw.events }
+
+// Watcher returns the watcher named name, or nil.
+func (mw *MultiWatcher) Watcher(name string) *Watcher { return mw.watchers[name] }
+
+// Names returns the names of the watchers, sorted.
+func (mw *MultiWatcher) Names() []string {
+	res := make([]string, 0, len(mw.watchers))
+	for name := range mw.watchers {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Stop stops every watcher and closes Events.
+func (mw *MultiWatcher) Stop() {
+	mw.stop.Do(func() {
+		close(mw.done)
+		for _, w := range mw.watchers {
+			w.Stop()
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiWatcher(t *testing.T) {
+	require := require.New(t)
+
+	localDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-multi-local")
+	require.NoError(err)
+	defer os.RemoveAll(localDirectory)
+	mountDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-multi-mount")
+	require.NoError(err)
+	defer os.RemoveAll(mountDirectory)
+
+	local := New(Notify(func(Event) {}))
+	mount := New(Notify(func(Event) {}), Exclude("**/*.tmp"))
+	multi := NewMultiWatcher(map[string]*Watcher{"local": local, "mount": mount}, 100)
+	defer multi.Stop()
+	require.Equal([]string{"local", "mount"}, multi.Names())
+	require.Equal(local, multi.Watcher("local"))
+
+	local.Add(localDirectory, false)
+	mount.Add(mountDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	localFile := filepath.Join(localDirectory, "a.txt")
+	mountFile := filepath.Join(mountDirectory, "b.txt")
+	require.NoError(ioutil.WriteFile(localFile, []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(mountFile, []byte("DATA"), 0644))
+
+	sources := make(map[string]string)
+T1:
+	for {
+		select {
+		case ev := <-multi.Events():
+			sources[ev.Name] = ev.Source
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal("local", sources[localFile])
+	require.Equal("mount", sources[mountFile])
+
+	multi.Stop()
+	select {
+	case _, ok := <-multi.Events():
+		require.False(ok)
+	case <-time.After(time.Second):
+		require.FailNow("events not closed after stop")
+	}
+}
@@ -0,0 +1,59 @@
+package dirwatch
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type mutes struct {
+	mx    sync.Mutex
+	until map[string]time.Time
+}
+
+// Mute drops the events of path and of everything under it for d, or until
+// Unmute is called if d is not positive. It helps to not get notified about
+// the changes a program makes itself. Dropped events are counted in
+// Stats.Muted.
+func (dw *Watcher) Mute(path string, d time.Duration) {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		dw.fail(err)
+		return
+	}
+	var until time.Time
+	if d > 0 {
+		until = dw.clock.Now().Add(d)
+	}
+	dw.mutes.mx.Lock()
+	defer dw.mutes.mx.Unlock()
+	dw.mutes.until[v] = until
+}
+
+// Unmute ends muting path.
+func (dw *Watcher) Unmute(path string) {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		dw.fail(err)
+		return
+	}
+	dw.mutes.mx.Lock()
+	defer dw.mutes.mx.Unlock()
+	delete(dw.mutes.until, v)
+}
+
+func (dw *Watcher) isMuted(name string) bool {
+	dw.mutes.mx.Lock()
+	defer dw.mutes.mx.Unlock()
+	now := dw.clock.Now()
+	for p, until := range dw.mutes.until {
+		if !until.IsZero() && now.After(until) {
+			delete(dw.mutes.until, p)
+			continue
+		}
+		if isUnder(name, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMute(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-mute")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	quiet := filepath.Join(rootDirectory, "quiet")
+	require.NoError(os.Mkdir(quiet, 0777))
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	collect := func() (names []string) {
+		for {
+			select {
+			case ev := <-events:
+				names = append(names, ev.Name)
+			case <-time.After(time.Millisecond * 300):
+				return
+			}
+		}
+	}
+
+	watcher.Mute(quiet, 0)
+	require.NoError(ioutil.WriteFile(filepath.Join(quiet, "mine.txt"), nil, 0777))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "theirs.txt"), nil, 0777))
+	names := collect()
+	require.Equal([]string{filepath.Join(rootDirectory, "theirs.txt")}, names)
+	require.Equal(uint64(1), watcher.Stats().Muted)
+
+	watcher.Unmute(quiet)
+	require.NoError(ioutil.WriteFile(filepath.Join(quiet, "again.txt"), nil, 0777))
+	require.Equal([]string{filepath.Join(quiet, "again.txt")}, collect())
+
+	watcher.Mute(quiet, time.Millisecond*100)
+	<-time.After(time.Millisecond * 200)
+	require.NoError(ioutil.WriteFile(filepath.Join(quiet, "expired.txt"), nil, 0777))
+	require.Equal([]string{filepath.Join(quiet, "expired.txt")}, collect())
+}
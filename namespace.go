@@ -0,0 +1,49 @@
+package dirwatch
+
+import "path/filepath"
+
+// NamespaceMap reports events in a logical namespace instead of the
+// physical one, e.g. for roots that make up a union of directories. m maps
+// added roots to logical prefixes, and the Name of an event attributed to
+// one of them (see Event.Root) is rewritten to be under its prefix. Root
+// keeps the physical root. Roots mapped to the same prefix all get their
+// events delivered, so the same logical name can be reported for several
+// files; no root takes precedence and Root tells them apart.
+func NamespaceMap(m map[string]string) Option {
+	return func(opt *options) {
+		opt.namespace = make(map[string]string, len(m))
+		for root, prefix := range m {
+			if abs, err := filepath.Abs(root); err == nil {
+				root = abs
+			}
+			opt.namespace[root] = filepath.Clean(prefix)
+		}
+	}
+}
+
+// toLogical rewrites the Name of ev into the NamespaceMap namespace.
+func (dw *Watcher) toLogical(ev Event) Event {
+	prefix, ok := dw.namespace[ev.Root]
+	if !ok {
+		return ev
+	}
+	rel, err := filepath.Rel(ev.Root, ev.Name)
+	if err != nil {
+		return ev
+	}
+	ev.Name = filepath.Join(prefix, rel)
+	return ev
+}
+
+// physical returns the path on disk of ev, undoing NamespaceMap.
+func (dw *Watcher) physical(ev Event) string {
+	prefix, ok := dw.namespace[ev.Root]
+	if !ok {
+		return ev.Name
+	}
+	rel, err := filepath.Rel(prefix, ev.Name)
+	if err != nil {
+		return ev.Name
+	}
+	return filepath.Join(ev.Root, rel)
+}
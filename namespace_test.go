@@ -0,0 +1,63 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceMap(t *testing.T) {
+	require := require.New(t)
+
+	base, err := ioutil.TempDir(os.TempDir(), "dirwatch-base")
+	require.NoError(err)
+	defer os.RemoveAll(base)
+	overlay, err := ioutil.TempDir(os.TempDir(), "dirwatch-overlay")
+	require.NoError(err)
+	defer os.RemoveAll(overlay)
+	require.NoError(os.Mkdir(filepath.Join(base, "etc"), 0755))
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		NamespaceMap(map[string]string{base: "/", overlay: "/"}),
+		SniffContentType())
+	defer watcher.Stop()
+	watcher.Add(base, true)
+	watcher.Add(overlay, true)
+	time.Sleep(time.Millisecond * 100)
+
+	next := func() Event {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second * 5):
+			require.FailNow("no event")
+		}
+		return Event{}
+	}
+
+	require.NoError(ioutil.WriteFile(filepath.Join(base, "etc", "hosts"), []byte("127.0.0.1 localhost"), 0644))
+	ev := next()
+	require.Equal(filepath.FromSlash("/etc/hosts"), ev.Name)
+	require.Equal(base, ev.Root)
+	// the content is still read from the physical file
+	require.Equal("text/plain; charset=utf-8", ev.ContentType)
+	for drained := false; !drained; {
+		select {
+		case ev := <-events:
+			require.Equal(filepath.FromSlash("/etc/hosts"), ev.Name)
+		case <-time.After(time.Millisecond * 300):
+			drained = true
+		}
+	}
+
+	require.NoError(ioutil.WriteFile(filepath.Join(overlay, "hosts"), []byte("DATA"), 0644))
+	ev = next()
+	require.Equal(filepath.FromSlash("/hosts"), ev.Name)
+	require.Equal(overlay, ev.Root)
+}
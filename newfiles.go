@@ -0,0 +1,33 @@
+package dirwatch
+
+// NewFilesOnly delivers only the first Create event of each file, dropping
+// every other event, e.g. for a pipeline that ingests each file once when it
+// appears. Once a file is removed or renamed, it is forgotten, so a file
+// re-created at the same path is reported again. Directories are not
+// reported.
+func NewFilesOnly() Option {
+	return func(opt *options) {
+		opt.newFilesOnly = true
+	}
+}
+
+// notNewFile reports whether ev is dropped by NewFilesOnly, and records
+// the files it lets through. It runs on the agent goroutine.
+func (dw *Watcher) notNewFile(ev Event, isdir bool) bool {
+	if !dw.newFilesOnly {
+		return false
+	}
+	if ev.Op&(Remove|Rename) != 0 {
+		dw.state.remove(stateKey{stateNewFile, ev.Name})
+		return true
+	}
+	if isdir || ev.Op&Create == 0 {
+		return true
+	}
+	key := stateKey{stateNewFile, ev.Name}
+	if _, ok := dw.state.get(key); ok {
+		return true
+	}
+	dw.state.set(key, nil)
+	return false
+}
@@ -0,0 +1,53 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFilesOnly(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-new-files")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), NewFilesOnly())
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	name := filepath.Join(rootDirectory, "text.txt")
+	steps := []func() error{
+		func() error { return ioutil.WriteFile(name, []byte("DATA"), 0666) },
+		func() error { return ioutil.WriteFile(name, []byte("MORE DATA"), 0666) },
+		func() error { return os.Remove(name) },
+		func() error { return ioutil.WriteFile(name, []byte("DATA"), 0666) },
+		func() error { return ioutil.WriteFile(name, []byte("MORE DATA"), 0666) },
+	}
+	for _, step := range steps {
+		require.NoError(step())
+		<-time.After(time.Millisecond * 50)
+	}
+
+	var calls int
+T1:
+	for {
+		select {
+		case ev := <-events:
+			require.Equal(name, ev.Name)
+			require.Equal(Create, ev.Op)
+			calls++
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(2, calls)
+}
@@ -0,0 +1,86 @@
+package dirwatch
+
+import (
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Op describes a set of file operations, as a bitmask.
+type Op uint32
+
+// The operations an Event can report.
+const (
+	Create Op = 1 << iota
+	Write
+	Remove
+	Rename
+	Chmod
+	// Xattr reports a change of the extended attributes of a file, as
+	// tracked by TrackXattrs.
+	Xattr
+	// Beat marks the synthetic events of the Heartbeat option.
+	Beat
+	// Readable reports data to read in a named pipe, as watched by
+	// WatchPipeData.
+	Readable
+)
+
+var opNames = []struct {
+	op   Op
+	name string
+}{
+	{Create, "CREATE"},
+	{Write, "WRITE"},
+	{Remove, "REMOVE"},
+	{Rename, "RENAME"},
+	{Chmod, "CHMOD"},
+	{Xattr, "XATTR"},
+	{Beat, "BEAT"},
+	{Readable, "READABLE"},
+}
+
+func (op Op) String() string {
+	var names []string
+	for _, v := range opNames {
+		if op&v.op == v.op {
+			names = append(names, v.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+var fsnotifyOps = []struct {
+	op  Op
+	raw fsnotify.Op
+}{
+	{Create, fsnotify.Create},
+	{Write, fsnotify.Write},
+	{Remove, fsnotify.Remove},
+	{Rename, fsnotify.Rename},
+	{Chmod, fsnotify.Chmod},
+}
+
+// OpFromFsnotify converts an fsnotify.Op to an Op.
+func OpFromFsnotify(raw fsnotify.Op) Op {
+	var op Op
+	for _, v := range fsnotifyOps {
+		if raw&v.raw == v.raw {
+			op |= v.op
+		}
+	}
+	return op
+}
+
+// FsnotifyOp returns the operations of the event as an fsnotify.Op.
+//
+// Deprecated: use Event.Op, which does not depend on fsnotify.
+func (ev Event) FsnotifyOp() fsnotify.Op {
+	var raw fsnotify.Op
+	for _, v := range fsnotifyOps {
+		if ev.Op&v.op == v.op {
+			raw |= v.raw
+		}
+	}
+	return raw
+}
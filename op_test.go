@@ -0,0 +1,33 @@
+package dirwatch
+
+import (
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpFromFsnotify(t *testing.T) {
+	require := require.New(t)
+
+	cases := []struct {
+		raw fsnotify.Op
+		op  Op
+		str string
+	}{
+		{fsnotify.Create, Create, "CREATE"},
+		{fsnotify.Write, Write, "WRITE"},
+		{fsnotify.Remove, Remove, "REMOVE"},
+		{fsnotify.Rename, Rename, "RENAME"},
+		{fsnotify.Chmod, Chmod, "CHMOD"},
+		{fsnotify.Create | fsnotify.Write, Create | Write, "CREATE|WRITE"},
+		{fsnotify.Remove | fsnotify.Chmod, Remove | Chmod, "REMOVE|CHMOD"},
+		{0, 0, ""},
+	}
+	for _, c := range cases {
+		op := OpFromFsnotify(c.raw)
+		require.Equal(c.op, op)
+		require.Equal(c.str, op.String())
+		require.Equal(c.raw, Event{Op: op}.FsnotifyOp())
+	}
+}
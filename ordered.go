@@ -0,0 +1,68 @@
+package dirwatch
+
+import "sync"
+
+// OrderedDelivery makes the watcher deliver events one at a time, in the
+// order they were observed, instead of concurrently. A slow notify function
+// then delays the following events, but never blocks the watcher itself.
+func OrderedDelivery() Option {
+	return func(opt *options) {
+		opt.ordered = true
+	}
+}
+
+// eventQueue is an unbounded FIFO of events waiting for ordered delivery.
+type eventQueue struct {
+	mx    sync.Mutex
+	items []Event
+	ready chan struct{}
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *eventQueue) push(ev Event) {
+	q.mx.Lock()
+	q.items = append(q.items, ev)
+	q.mx.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// swap returns the queued events, replacing them with spare, emptied, to
+// reuse its storage.
+func (q *eventQueue) swap(spare []Event) []Event {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	items := q.items
+	q.items = spare[:0]
+	return items
+}
+
+func (q *eventQueue) take() []Event {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// deliverOrdered delivers the queued events, one after another, until the
+// watcher stops.
+func (dw *Watcher) deliverOrdered() {
+	for {
+		select {
+		case <-dw.stopped():
+			return
+		case <-dw.queue.ready:
+		}
+		for _, ev := range dw.queue.take() {
+			dw.dispatch(ev)
+			dw.undelivered.done(1)
+		}
+	}
+}
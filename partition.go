@@ -0,0 +1,19 @@
+package dirwatch
+
+// partitionBuffer is the buffer of each channel returned by Partition.
+const partitionBuffer = 100
+
+// Partition returns a channel per operation, each receiving the delivered
+// events that have that operation, so a pipeline can handle each one in a
+// stage of its own. An event with several operations goes to each of their
+// channels. Like Subscribe, the channels are buffered, by 100 events, and
+// one that is full misses events instead of holding up the watcher. They
+// are closed when the watcher stops.
+func (dw *Watcher) Partition() (creates, writes, removes, renames, chmods <-chan Event) {
+	partition := func(op Op) <-chan Event {
+		events := make(chan Event, partitionBuffer)
+		dw.subscribe(events, op)
+		return events
+	}
+	return partition(Create), partition(Write), partition(Remove), partition(Rename), partition(Chmod)
+}
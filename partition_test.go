@@ -0,0 +1,57 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartition(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-partition")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fp := filepath.Join(rootDirectory, "data.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+	creates, writes, removes, renames, chmods := watcher.Partition()
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Create}
+	select {
+	case ev := <-creates:
+		require.Equal(fp, ev.Name)
+		require.Equal(Create, ev.Op)
+	case <-time.After(time.Second * 5):
+		require.FailNow("create was not partitioned")
+	}
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write | fsnotify.Chmod}
+	for _, events := range []<-chan Event{writes, chmods} {
+		select {
+		case ev := <-events:
+			require.Equal(Write|Chmod, ev.Op)
+		case <-time.After(time.Second * 5):
+			require.FailNow("event with two operations was not partitioned")
+		}
+	}
+	require.Len(creates, 0)
+	require.Len(removes, 0)
+	require.Len(renames, 0)
+
+	watcher.Stop()
+	for _, events := range []<-chan Event{creates, writes, removes, renames, chmods} {
+		_, ok := <-events
+		require.False(ok)
+	}
+}
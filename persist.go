@@ -0,0 +1,61 @@
+package dirwatch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+type savedState struct {
+	Roots []savedRoot `json:"roots"`
+}
+
+type savedRoot struct {
+	Path      string      `json:"path"`
+	Recursive bool        `json:"recursive"`
+	Tag       interface{} `json:"tag,omitempty"`
+}
+
+// SaveState writes the added roots, with their recursive flags and tags, to
+// w as JSON, for RestoreState. Tags must be JSON-serializable.
+func (dw *Watcher) SaveState(w io.Writer) error {
+	var state savedState
+	err := dw.call(func(backend) {
+		for p, r := range dw.roots {
+			state.Roots = append(state.Roots, savedRoot{Path: p, Recursive: r.recursive, Tag: r.tag})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(state.Roots, func(i, j int) bool { return state.Roots[i].Path < state.Roots[j].Path })
+	return errors.WithStack(json.NewEncoder(w).Encode(state))
+}
+
+// RestoreState creates a new *Watcher and adds the roots saved by SaveState
+// to it, e.g. for a daemon to resume watching what it watched before a
+// restart. Tags are restored as decoded JSON values, so a number becomes a
+// float64 and a struct a map[string]interface{}. Roots that no longer exist
+// are skipped, unless StrictRoots is used.
+func RestoreState(r io.Reader, notify func(Event), opt ...Option) (*Watcher, error) {
+	var state savedState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, errors.Wrap(err, "restore state")
+	}
+	dw, err := NewWithError(append([]Option{Notify(notify)}, opt...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range state.Roots {
+		recursive := root.Recursive
+		err := dw.addRoot(context.Background(), fspath{path: root.Path, recursive: &recursive, tag: root.Tag})
+		if err != nil {
+			dw.Stop()
+			return nil, errors.Wrapf(err, "restore state: root %s", root.Path)
+		}
+	}
+	return dw, nil
+}
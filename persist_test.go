@@ -0,0 +1,60 @@
+package dirwatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveRestoreState(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-persist")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	docs := filepath.Join(rootDirectory, "docs")
+	inbox := filepath.Join(rootDirectory, "inbox")
+	require.NoError(os.MkdirAll(filepath.Join(docs, "sub"), 0777))
+	require.NoError(os.MkdirAll(inbox, 0777))
+
+	watcher := New(Notify(func(Event) {}))
+	watcher.AddWithTag(docs, true, "docs")
+	watcher.Add(inbox, false)
+	<-time.After(time.Millisecond * 50)
+
+	var buf bytes.Buffer
+	require.NoError(watcher.SaveState(&buf))
+	watcher.Stop()
+
+	var events = make(chan Event, 100)
+	restored, err := RestoreState(&buf, func(ev Event) { events <- ev })
+	require.NoError(err)
+	defer restored.Stop()
+
+	recursive, known := restored.IsRecursive(docs)
+	require.True(known)
+	require.True(recursive)
+	recursive, known = restored.IsRecursive(inbox)
+	require.True(known)
+	require.False(recursive)
+	<-time.After(time.Millisecond * 50)
+
+	fp := filepath.Join(docs, "sub", "a.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	select {
+	case ev := <-events:
+		require.Equal(fp, ev.Name)
+		require.Equal("docs", ev.Tag)
+	case <-time.After(time.Second * 5):
+		require.FailNow("no event from a restored root")
+	}
+
+	_, err = RestoreState(strings.NewReader("not json"), func(Event) {})
+	require.Error(err)
+}
@@ -0,0 +1,44 @@
+package dirwatch
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pipeWaiter waits for the writes to a pipe with kqueue, whose EV_CLEAR
+// makes it report each write, even while earlier data is unread.
+type pipeWaiter struct {
+	kq     int
+	events []unix.Kevent_t
+}
+
+func newPipeWaiter(fd int) (*pipeWaiter, error) {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return nil, err
+	}
+	var change unix.Kevent_t
+	unix.SetKevent(&change, fd, unix.EVFILT_READ, unix.EV_ADD|unix.EV_CLEAR)
+	if _, err := unix.Kevent(kq, []unix.Kevent_t{change}, nil, nil); err != nil {
+		unix.Close(kq)
+		return nil, err
+	}
+	return &pipeWaiter{kq: kq, events: make([]unix.Kevent_t, 1)}, nil
+}
+
+// wait reports whether the pipe got written to within timeout.
+func (w *pipeWaiter) wait(timeout time.Duration) (bool, error) {
+	ts := unix.NsecToTimespec(int64(timeout))
+	n, err := unix.Kevent(w.kq, nil, w.events, &ts)
+	if err == unix.EINTR {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// a hang up of the last writer wakes it up too, with nothing to read
+	return n > 0 && w.events[0].Data > 0, nil
+}
+
+func (w *pipeWaiter) close() { unix.Close(w.kq) }
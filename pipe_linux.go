@@ -0,0 +1,42 @@
+package dirwatch
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pipeWaiter waits for the writes to a pipe with edge-triggered epoll,
+// which wakes up on every write, even while earlier data is unread.
+type pipeWaiter struct {
+	epfd   int
+	events []unix.EpollEvent
+}
+
+func newPipeWaiter(fd int) (*pipeWaiter, error) {
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	ev := unix.EpollEvent{Events: unix.EPOLLIN | unix.EPOLLET, Fd: int32(fd)}
+	if err := unix.EpollCtl(epfd, unix.EPOLL_CTL_ADD, fd, &ev); err != nil {
+		unix.Close(epfd)
+		return nil, err
+	}
+	return &pipeWaiter{epfd: epfd, events: make([]unix.EpollEvent, 1)}, nil
+}
+
+// wait reports whether the pipe got written to within timeout.
+func (w *pipeWaiter) wait(timeout time.Duration) (bool, error) {
+	n, err := unix.EpollWait(w.epfd, w.events, int(timeout/time.Millisecond))
+	if err == unix.EINTR {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// a hang up of the last writer wakes it up too
+	return n > 0 && w.events[0].Events&unix.EPOLLIN != 0, nil
+}
+
+func (w *pipeWaiter) close() { unix.Close(w.epfd) }
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package dirwatch
+
+// watchPipe does nothing, since WatchPipeData is not supported here.
+func (dw *Watcher) watchPipe(path string) {}
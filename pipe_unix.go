@@ -0,0 +1,83 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package dirwatch
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// WatchPipeData reports data written to the named pipe path, a FIFO, with
+// a synthetic Readable event of path, for consumers of producers that
+// write to a pipe. The watcher waits for the writes, using epoll on Linux
+// and kqueue on macOS, and never reads the data, leaving it to the
+// consumer; data sitting unread is not reported again. Writes that come
+// in quick succession may be reported by a single event. The events go
+// through the delivery options like any other, but skip the filters, which
+// drop the events of special files. It is supported on Linux and macOS
+// only.
+func WatchPipeData(path string) Option {
+	return func(opt *options) {
+		opt.pipes = append(opt.pipes, path)
+	}
+}
+
+// pipeWait is how long a pipe watched by WatchPipeData is waited on at
+// once, before checking whether the watcher has stopped.
+const pipeWait = time.Millisecond * 50
+
+// watchPipe reports the data written to the named pipe path, until the
+// watcher stops.
+func (dw *Watcher) watchPipe(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	// non-blocking, so that opening does not wait for a writer
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		dw.fail(errors.Wrapf(classify(err), "watching pipe %s", path))
+		return
+	}
+	defer unix.Close(fd)
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		dw.fail(errors.Wrapf(err, "watching pipe %s", path))
+		return
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFIFO {
+		dw.fail(errors.Errorf("watching pipe %s: not a named pipe", path))
+		return
+	}
+	w, err := newPipeWaiter(fd)
+	if err != nil {
+		dw.fail(errors.Wrapf(err, "watching pipe %s", path))
+		return
+	}
+	defer w.close()
+
+	for {
+		select {
+		case <-dw.stopped():
+			return
+		default:
+		}
+		written, err := w.wait(pipeWait)
+		if err != nil {
+			dw.fail(errors.Wrapf(err, "watching pipe %s", path))
+			return
+		}
+		if !written {
+			continue
+		}
+		ev := Event{Name: path, Op: Readable, Time: dw.clock.Now()}
+		dw.call(func(backend) {
+			if !dw.draining {
+				dw.deliver(dw.attribute(ev))
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package dirwatch
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchPipeData(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-pipe")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fifo := filepath.Join(rootDirectory, "pipe")
+	require.NoError(syscall.Mkfifo(fifo, 0644))
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		WatchPipeData(fifo))
+	defer watcher.Stop()
+
+	// opening for writing fails until the watcher has it open for reading
+	var w *os.File
+	for start := time.Now(); w == nil; {
+		w, err = os.OpenFile(fifo, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			require.True(time.Since(start) < time.Second*5, err.Error())
+			<-time.After(time.Millisecond * 10)
+		}
+	}
+	defer w.Close()
+	r, err := os.OpenFile(fifo, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	require.NoError(err)
+	defer r.Close()
+
+	readable := func() {
+		select {
+		case ev := <-events:
+			require.Equal(fifo, ev.Name)
+			require.Equal(Readable, ev.Op)
+		case <-time.After(time.Second * 5):
+			require.FailNow("readiness event not received")
+		}
+	}
+
+	select {
+	case ev := <-events:
+		require.FailNow("event before any data", ev.Op.String())
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	_, err = w.Write([]byte("DATA"))
+	require.NoError(err)
+	readable()
+
+	// the data is left for the consumer, and sitting unread is reported
+	// only once
+	select {
+	case ev := <-events:
+		require.FailNow("reported again", ev.Op.String())
+	case <-time.After(time.Millisecond * 300):
+	}
+	buf := make([]byte, 16)
+	n, err := io.ReadAtLeast(r, buf, 4)
+	require.NoError(err)
+	require.Equal("DATA", string(buf[:n]))
+
+	_, err = w.Write([]byte("MORE"))
+	require.NoError(err)
+	readable()
+}
@@ -0,0 +1,239 @@
+package dirwatch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// PersistQueue appends every delivered event, as JSON, to a log in dir,
+// giving it a sequence number in Event.Seq. Once an event is processed, it
+// should be acknowledged with Ack. The events not acknowledged when the
+// process ends, e.g. because it crashed, are delivered again by the next
+// watcher created with the same dir, before new events, for at-least-once
+// processing. The log is split in segments, which are removed once all
+// their events are acknowledged. Writes are not synced to disk, so the log
+// survives a crash of the process, but not of the machine. Only one
+// watcher at a time may use dir.
+func PersistQueue(dir string) Option {
+	return func(opt *options) {
+		opt.persistDir = dir
+	}
+}
+
+// segmentEvents is the number of events per log segment.
+const segmentEvents = 1000
+
+const (
+	segmentSuffix = ".log"
+	ackedFile     = "acked"
+)
+
+type persistQueue struct {
+	mx       sync.Mutex
+	dir      string
+	next     uint64
+	acked    uint64
+	ahead    map[uint64]bool
+	segments []uint64
+	current  *os.File
+	count    int
+	closed   bool
+	replay   []Event
+}
+
+// openQueue opens the log of PersistQueue, loading the events to deliver
+// again.
+func (dw *Watcher) openQueue() error {
+	if dw.persistDir == "" {
+		return nil
+	}
+	q := &persistQueue{dir: dw.persistDir, next: 1, ahead: make(map[uint64]bool)}
+	if err := os.MkdirAll(q.dir, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(q.dir, ackedFile))
+	switch {
+	case err == nil:
+		q.acked, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "persist queue: acked")
+		}
+		q.next = q.acked + 1
+	case !os.IsNotExist(err):
+		return errors.WithStack(err)
+	}
+
+	names, err := filepath.Glob(filepath.Join(q.dir, "*"+segmentSuffix))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		first, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		q.segments = append(q.segments, first)
+		if err := q.load(name); err != nil {
+			return err
+		}
+	}
+	q.truncate()
+	dw.pqueue = q
+	return nil
+}
+
+// load reads the events of the segment file name.
+func (q *persistQueue) load(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			// a line cut short by a crash
+			continue
+		}
+		if ev.Seq >= q.next {
+			q.next = ev.Seq + 1
+		}
+		if ev.Seq > q.acked {
+			q.replay = append(q.replay, ev)
+		}
+	}
+	return errors.WithStack(scanner.Err())
+}
+
+// persist gives ev its sequence number and appends it to the log. It runs
+// on the agent goroutine.
+func (dw *Watcher) persist(ev Event) Event {
+	q := dw.pqueue
+	if q == nil || ev.Op&Beat != 0 {
+		return ev
+	}
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	if q.closed {
+		return ev
+	}
+	ev.Seq = q.next
+	q.next++
+	if err := q.append(ev); err != nil {
+		dw.fail(err)
+	}
+	return ev
+}
+
+func (q *persistQueue) append(ev Event) error {
+	if q.current == nil || q.count >= segmentEvents {
+		if q.current != nil {
+			q.current.Close()
+		}
+		name := filepath.Join(q.dir, fmt.Sprintf("%020d%s", ev.Seq, segmentSuffix))
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			q.current = nil
+			return errors.WithStack(err)
+		}
+		q.current, q.count = f, 0
+		q.segments = append(q.segments, ev.Seq)
+		q.truncate()
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return errors.Wrapf(err, "persist %s", ev.Name)
+	}
+	q.count++
+	_, err = q.current.Write(append(data, '\n'))
+	return errors.WithStack(err)
+}
+
+// truncate removes the segments whose events are all acknowledged, except
+// the last one.
+func (q *persistQueue) truncate() {
+	for len(q.segments) > 1 && q.segments[1]-1 <= q.acked {
+		os.Remove(filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.segments[0], segmentSuffix)))
+		q.segments = q.segments[1:]
+	}
+}
+
+// Ack marks the event with sequence number seq, as set by PersistQueue,
+// as processed, so it is not delivered again.
+func (dw *Watcher) Ack(seq uint64) error {
+	q := dw.pqueue
+	if q == nil {
+		return errors.New("no persist queue")
+	}
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	if seq <= q.acked {
+		return nil
+	}
+	q.ahead[seq] = true
+	before := q.acked
+	for q.ahead[q.acked+1] {
+		delete(q.ahead, q.acked+1)
+		q.acked++
+	}
+	if q.acked == before {
+		return nil
+	}
+	tmp := filepath.Join(q.dir, ackedFile+".tmp")
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatUint(q.acked, 10)), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, ackedFile)); err != nil {
+		return errors.WithStack(err)
+	}
+	q.truncate()
+	return nil
+}
+
+// replayQueue delivers again the events that were not acknowledged.
+func (dw *Watcher) replayQueue() {
+	q := dw.pqueue
+	if q == nil {
+		return
+	}
+	q.mx.Lock()
+	replay := q.replay
+	q.replay = nil
+	q.mx.Unlock()
+	if len(replay) == 0 {
+		return
+	}
+	dw.call(func(backend) {
+		for _, ev := range replay {
+			dw.send(ev)
+		}
+	})
+}
+
+// closeQueue closes the current segment of the log.
+func (dw *Watcher) closeQueue() {
+	q := dw.pqueue
+	if q == nil {
+		return
+	}
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	q.closed = true
+	if q.current != nil {
+		q.current.Close()
+		q.current = nil
+	}
+}
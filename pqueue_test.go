@@ -0,0 +1,83 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistQueue(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-pqueue")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	queueDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-pqueue-log")
+	require.NoError(err)
+	defer os.RemoveAll(queueDirectory)
+
+	collect := func(events chan Event) []Event {
+		var res []Event
+		for {
+			select {
+			case ev := <-events:
+				res = append(res, ev)
+			case <-time.After(time.Millisecond * 300):
+				return res
+			}
+		}
+	}
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), PersistQueue(queueDirectory))
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	handled := filepath.Join(rootDirectory, "handled.txt")
+	crashed := filepath.Join(rootDirectory, "crashed.txt")
+	require.NoError(ioutil.WriteFile(handled, []byte("DATA"), 0644))
+	<-time.After(time.Millisecond * 50)
+	require.NoError(ioutil.WriteFile(crashed, []byte("DATA"), 0644))
+
+	unacked := make(map[uint64]string)
+	for _, ev := range collect(events) {
+		require.NotZero(ev.Seq)
+		if ev.Name == handled {
+			require.NoError(watcher.Ack(ev.Seq))
+		} else {
+			unacked[ev.Seq] = ev.Name
+		}
+	}
+	require.NotEmpty(unacked)
+	// the process crashes before handling crashed.txt
+	watcher.Stop()
+
+	events = make(chan Event, 100)
+	watcher = New(Notify(func(ev Event) { events <- ev }), PersistQueue(queueDirectory))
+	replayed := make(map[uint64]string)
+	for _, ev := range collect(events) {
+		replayed[ev.Seq] = ev.Name
+		require.NoError(watcher.Ack(ev.Seq))
+	}
+	require.Equal(unacked, replayed)
+	watcher.Stop()
+
+	events = make(chan Event, 100)
+	watcher = New(Notify(func(ev Event) { events <- ev }), PersistQueue(queueDirectory))
+	defer watcher.Stop()
+	require.Empty(collect(events))
+
+	// sequence numbers go on after the last one
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "next.txt"), []byte("DATA"), 0644))
+	next := collect(events)
+	require.NotEmpty(next)
+	for seq := range unacked {
+		require.True(next[0].Seq > seq)
+	}
+}
@@ -0,0 +1,24 @@
+package dirwatch
+
+// PresetSet is a set of exclude patterns for a common kind of clutter.
+// All the presets use **/ patterns, which match at any depth.
+type PresetSet []string
+
+// Exclude presets.
+var (
+	PresetVCS         = PresetSet{"**/.git", "**/.hg", "**/.svn", "**/.bzr"}
+	PresetNodeModules = PresetSet{"**/node_modules"}
+	PresetPythonCache = PresetSet{"**/__pycache__", "**/*.pyc", "**/.pytest_cache", "**/.mypy_cache"}
+	PresetOSFiles     = PresetSet{"**/.DS_Store", "**/Thumbs.db", "**/desktop.ini"}
+	PresetBuildOutput = PresetSet{"**/build", "**/dist", "**/target"}
+)
+
+// ExcludePresets adds the patterns of the given presets to the exclude
+// patterns.
+func ExcludePresets(presets ...PresetSet) Option {
+	return func(opt *options) {
+		for _, v := range presets {
+			opt.exclude = append(opt.exclude, v...)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcludePresets(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-presets")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	excluded := map[string]string{
+		filepath.Join("src", ".git"):                  "**/.git",
+		filepath.Join("web", "lib", "node_modules"):   "**/node_modules",
+		filepath.Join("src", "pkg", "__pycache__"):    "**/__pycache__",
+		filepath.Join("src", "pkg", "mod.pyc"):        "**/*.pyc",
+		filepath.Join(".DS_Store"):                    "**/.DS_Store",
+		filepath.Join("web", "dist"):                  "**/dist",
+		filepath.Join("deep", "a", "b", "c", "build"): "**/build",
+	}
+	included := []string{
+		"src",
+		filepath.Join("src", "pkg"),
+		filepath.Join("web", "lib"),
+		filepath.Join("src", "git"),
+	}
+	for _, v := range append(included, filepath.Join("deep", "a", "b", "c")) {
+		require.NoError(os.MkdirAll(filepath.Join(rootDirectory, v), 0777))
+	}
+	for v := range excluded {
+		require.NoError(os.MkdirAll(filepath.Join(rootDirectory, v), 0777))
+	}
+
+	watcher := New(
+		Notify(func(Event) {}),
+		ExcludePresets(PresetVCS, PresetNodeModules, PresetPythonCache, PresetOSFiles, PresetBuildOutput))
+	defer watcher.Stop()
+
+	for v, ptrn := range excluded {
+		ok, reason := watcher.WouldWatch(filepath.Join(rootDirectory, v))
+		require.False(ok, v)
+		require.Equal("excluded by pattern "+ptrn, reason)
+	}
+	for _, v := range included {
+		ok, reason := watcher.WouldWatch(filepath.Join(rootDirectory, v))
+		require.True(ok, reason)
+	}
+}
@@ -0,0 +1,81 @@
+package dirwatch
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// addQueue is an unbounded priority queue of pending adds, feeding onAdd.
+// Higher priorities are popped first and equal priorities keep the order
+// in which they were pushed.
+type addQueue struct {
+	mu    sync.Mutex
+	items addHeap
+	seq   uint64
+	ready chan struct{}
+}
+
+func newAddQueue() *addQueue {
+	return &addQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *addQueue) push(fsp ...fspath) {
+	q.mu.Lock()
+	for _, v := range fsp {
+		q.seq++
+		heap.Push(&q.items, queuedPath{fspath: v, seq: q.seq})
+	}
+	q.mu.Unlock()
+	q.signal()
+}
+
+// signal marks the queue ready, if it is not already.
+func (q *addQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *addQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *addQueue) pop() (fspath, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return fspath{}, false
+	}
+	return heap.Pop(&q.items).(queuedPath).fspath, true
+}
+
+type queuedPath struct {
+	fspath
+	seq uint64
+}
+
+type addHeap []queuedPath
+
+func (h addHeap) Len() int { return len(h) }
+
+func (h addHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h addHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *addHeap) Push(x interface{}) { *h = append(*h, x.(queuedPath)) }
+
+func (h *addHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
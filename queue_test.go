@@ -0,0 +1,28 @@
+package dirwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddQueueOrder(t *testing.T) {
+	require := require.New(t)
+
+	q := newAddQueue()
+	q.push(fspath{path: "/low1"})
+	q.push(fspath{path: "/high1", priority: 10})
+	q.push(fspath{path: "/low2"})
+	q.push(fspath{path: "/mid", priority: 5})
+	q.push(fspath{path: "/high2", priority: 10})
+
+	var order []string
+	for {
+		fsp, ok := q.pop()
+		if !ok {
+			break
+		}
+		order = append(order, fsp.path)
+	}
+	require.Equal([]string{"/high1", "/high2", "/mid", "/low1", "/low2"}, order)
+}
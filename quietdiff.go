@@ -0,0 +1,108 @@
+package dirwatch
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dc0d/retry"
+)
+
+// OnQuietDiff collects the delivered events and, once there has been none
+// for quiet, calls fn with the paths created, modified and deleted since
+// its last call, each sorted, e.g. for a sync engine. The changes of a path
+// are netted: a path created then deleted is not reported, a path created
+// then written is reported as created, and a path deleted then created
+// again is reported as modified. A rename is reported as the deletion of
+// the old path and the creation of the new one.
+func OnQuietDiff(quiet time.Duration, fn func(created, modified, deleted []string)) Option {
+	return func(opt *options) {
+		opt.diffQuiet = quiet
+		opt.diffFn = fn
+	}
+}
+
+type diffKind int
+
+const (
+	diffCreated diffKind = iota + 1
+	diffModified
+	diffDeleted
+)
+
+type quietDiff struct {
+	mx      sync.Mutex
+	changes map[string]diffKind
+	timer   timer
+}
+
+// trackDiff records ev for OnQuietDiff and restarts the quiet period.
+func (dw *Watcher) trackDiff(ev Event) {
+	if dw.diffFn == nil || ev.Name == "" {
+		return
+	}
+	dw.diff.mx.Lock()
+	defer dw.diff.mx.Unlock()
+
+	prev, seen := dw.diff.changes[ev.Name]
+	switch {
+	case ev.Op&(Remove|Rename) != 0:
+		if prev == diffCreated {
+			delete(dw.diff.changes, ev.Name)
+		} else {
+			dw.diff.changes[ev.Name] = diffDeleted
+		}
+	case !seen:
+		if ev.Op&Create != 0 {
+			dw.diff.changes[ev.Name] = diffCreated
+		} else {
+			dw.diff.changes[ev.Name] = diffModified
+		}
+	case prev == diffDeleted:
+		dw.diff.changes[ev.Name] = diffModified
+	}
+
+	if dw.diff.timer != nil {
+		dw.diff.timer.Stop()
+	}
+	var t timer
+	t = dw.clock.AfterFunc(dw.diffQuiet, func() {
+		dw.diff.mx.Lock()
+		if dw.diff.timer != t {
+			dw.diff.mx.Unlock()
+			return
+		}
+		changes := dw.diff.changes
+		dw.diff.changes = make(map[string]diffKind)
+		dw.diff.timer = nil
+		dw.diff.mx.Unlock()
+		dw.flushDiff(changes)
+	})
+	dw.diff.timer = t
+}
+
+func (dw *Watcher) flushDiff(changes map[string]diffKind) {
+	if len(changes) == 0 {
+		return
+	}
+	select {
+	case <-dw.stopped():
+		return
+	default:
+	}
+	var created, modified, deleted []string
+	for p, kind := range changes {
+		switch kind {
+		case diffCreated:
+			created = append(created, p)
+		case diffModified:
+			modified = append(modified, p)
+		case diffDeleted:
+			deleted = append(deleted, p)
+		}
+	}
+	sort.Strings(created)
+	sort.Strings(modified)
+	sort.Strings(deleted)
+	retry.Try(func() error { dw.diffFn(created, modified, deleted); return nil })
+}
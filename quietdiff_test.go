@@ -0,0 +1,68 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnQuietDiff(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-quietdiff")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	type diff struct{ created, modified, deleted []string }
+	var diffs = make(chan diff, 10)
+	watcher := New(
+		Notify(func(Event) {}),
+		OnQuietDiff(time.Second, func(created, modified, deleted []string) {
+			diffs <- diff{created, modified, deleted}
+		}),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+
+	path := func(name string) string { return filepath.Join(rootDirectory, name) }
+	burst := []fsnotify.Event{
+		{Name: path("new.txt"), Op: fsnotify.Create},
+		{Name: path("new.txt"), Op: fsnotify.Write},
+		{Name: path("temp.txt"), Op: fsnotify.Create},
+		{Name: path("temp.txt"), Op: fsnotify.Remove},
+		{Name: path("edited.txt"), Op: fsnotify.Write},
+		{Name: path("edited.txt"), Op: fsnotify.Chmod},
+		{Name: path("gone.txt"), Op: fsnotify.Remove},
+		{Name: path("replaced.txt"), Op: fsnotify.Remove},
+		{Name: path("replaced.txt"), Op: fsnotify.Create},
+	}
+	for i, ev := range burst {
+		fake.events <- ev
+		clock.waitTimers(i + 1)
+	}
+	clock.Advance(time.Millisecond * 500)
+	require.Len(diffs, 0)
+	clock.Advance(time.Millisecond * 500)
+
+	require.Len(diffs, 1)
+	d := <-diffs
+	require.Equal([]string{path("new.txt")}, d.created)
+	require.Equal([]string{path("edited.txt"), path("replaced.txt")}, d.modified)
+	require.Equal([]string{path("gone.txt")}, d.deleted)
+
+	// the changes are reset after each report
+	fake.events <- fsnotify.Event{Name: path("new.txt"), Op: fsnotify.Write}
+	clock.waitTimers(len(burst) + 1)
+	clock.Advance(time.Second)
+	require.Len(diffs, 1)
+	d = <-diffs
+	require.Empty(d.created)
+	require.Equal([]string{path("new.txt")}, d.modified)
+}
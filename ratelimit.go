@@ -0,0 +1,91 @@
+package dirwatch
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PerDirRateLimit limits the events delivered for the entries of each
+// directory to eventsPerSec, allowing bursts of up to eventsPerSec. Every
+// directory has its own limit, so a busy directory gets throttled without
+// affecting the others. Dropped events are counted per directory in
+// Stats.Throttled.
+func PerDirRateLimit(eventsPerSec int) Option {
+	return func(opt *options) {
+		opt.dirRate = eventsPerSec
+	}
+}
+
+// maxIdleBuckets is the number of token buckets kept before the ones of
+// idle directories get dropped.
+const maxIdleBuckets = 1024
+
+type dirLimits struct {
+	mx      sync.Mutex
+	buckets map[string]*tokenBucket
+	dropped map[string]uint64
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// throttled reports whether ev is dropped by PerDirRateLimit, and counts it
+// if so.
+func (dw *Watcher) throttled(ev Event) bool {
+	if dw.dirRate <= 0 {
+		return false
+	}
+	dir := filepath.Dir(ev.Name)
+	now := dw.clock.Now()
+	rate := float64(dw.dirRate)
+
+	dw.limits.mx.Lock()
+	defer dw.limits.mx.Unlock()
+
+	b, ok := dw.limits.buckets[dir]
+	if !ok {
+		if len(dw.limits.buckets) >= maxIdleBuckets {
+			dw.dropIdleBuckets(now)
+		}
+		b = &tokenBucket{tokens: rate, last: now}
+		dw.limits.buckets[dir] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > rate {
+		b.tokens = rate
+	}
+	b.last = now
+	if b.tokens < 1 {
+		dw.limits.dropped[dir]++
+		return true
+	}
+	b.tokens--
+	return false
+}
+
+// dropIdleBuckets removes the buckets that would be full by now, since a
+// new bucket starts full anyway.
+func (dw *Watcher) dropIdleBuckets(now time.Time) {
+	rate := float64(dw.dirRate)
+	for dir, b := range dw.limits.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= rate {
+			delete(dw.limits.buckets, dir)
+		}
+	}
+}
+
+func (dw *Watcher) throttledCounts() map[string]uint64 {
+	dw.limits.mx.Lock()
+	defer dw.limits.mx.Unlock()
+	if len(dw.limits.dropped) == 0 {
+		return nil
+	}
+	res := make(map[string]uint64, len(dw.limits.dropped))
+	for dir, n := range dw.limits.dropped {
+		res[dir] = n
+	}
+	return res
+}
@@ -0,0 +1,56 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerDirRateLimit(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-rate")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	noisy := filepath.Join(rootDirectory, "noisy")
+	quiet := filepath.Join(rootDirectory, "quiet")
+	require.NoError(os.Mkdir(noisy, 0777))
+	require.NoError(os.Mkdir(quiet, 0777))
+
+	events := make(chan Event, 1000)
+	watcher := New(Notify(func(ev Event) { events <- ev }), PerDirRateLimit(5))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(ioutil.WriteFile(filepath.Join(noisy, fmt.Sprintf("%d.log", i)), []byte("DATA"), 0666))
+	}
+	require.NoError(ioutil.WriteFile(filepath.Join(quiet, "rare.txt"), []byte("DATA"), 0666))
+
+	var fromNoisy, fromQuiet int
+T1:
+	for {
+		select {
+		case ev := <-events:
+			switch filepath.Dir(ev.Name) {
+			case noisy:
+				fromNoisy++
+			case quiet:
+				fromQuiet++
+			}
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.True(fromQuiet > 0)
+	require.True(fromNoisy < 20, fromNoisy)
+	require.True(watcher.Stats().Throttled[noisy] > 0)
+	require.Zero(watcher.Stats().Throttled[quiet])
+}
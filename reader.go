@@ -0,0 +1,61 @@
+package dirwatch
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// NotifyWithReader sets a notify callback that gets the content of the
+// file of Create and Write events along, for consumers that read changed
+// files and would otherwise race with the writes and removals that follow.
+// The watcher reads up to max bytes of the file right before calling fn,
+// so fn reads a snapshot that later changes do not affect. r is nil for
+// the other events, for directories, for files larger than max and for
+// files that are gone by then, which are flagged with Event.Gone. The
+// watcher closes r once fn returns, so fn must not keep it.
+func NotifyWithReader(max int64, fn func(ev Event, r io.ReadCloser)) Option {
+	return func(opt *options) {
+		opt.readerMax = max
+		opt.readerFn = fn
+		opt.notify = nil
+		opt.notifyCtx = nil
+	}
+}
+
+// notifyWithReader calls the NotifyWithReader callback for ev.
+func (dw *Watcher) notifyWithReader(ev Event) error {
+	var r io.ReadCloser
+	if ev.Op&(Create|Write) != 0 {
+		content, gone := dw.snapshot(dw.physical(ev))
+		ev.Gone = gone
+		if content != nil {
+			r = ioutil.NopCloser(bytes.NewReader(content))
+			defer r.Close()
+		}
+	}
+	dw.readerFn(ev, r)
+	return nil
+}
+
+// snapshot returns the content of the file name, unless it is not a
+// regular file or it is larger than NotifyWithReader allows, and reports
+// whether it is gone.
+func (dw *Watcher) snapshot(name string) (content []byte, gone bool) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, os.IsNotExist(err)
+	}
+	defer f.Close()
+	inf, err := f.Stat()
+	if err != nil || !inf.Mode().IsRegular() {
+		return nil, false
+	}
+	// the size can change after the stat, so the read is bounded too
+	content, err = ioutil.ReadAll(io.LimitReader(f, dw.readerMax+1))
+	if err != nil || int64(len(content)) > dw.readerMax {
+		return nil, false
+	}
+	return content, false
+}
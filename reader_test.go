@@ -0,0 +1,73 @@
+package dirwatch
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyWithReader(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-reader")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	type read struct {
+		ev      Event
+		content string
+		nilR    bool
+	}
+	var reads = make(chan read, 100)
+	watcher := New(NotifyWithReader(16, func(ev Event, r io.ReadCloser) {
+		res := read{ev: ev, nilR: r == nil}
+		if r != nil {
+			content, err := ioutil.ReadAll(r)
+			if err == nil {
+				res.content = string(content)
+			}
+		}
+		reads <- res
+	}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 100)
+
+	next := func(name string) read {
+		for {
+			select {
+			case res := <-reads:
+				if res.ev.Name == name && res.ev.Op&(Create|Write) != 0 && (res.content != "" || res.nilR) {
+					return res
+				}
+			case <-time.After(time.Second * 5):
+				require.FailNow("event not received", name)
+			}
+		}
+	}
+
+	fp := filepath.Join(rootDirectory, "small.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	res := next(fp)
+	require.Equal("DATA", res.content)
+	require.False(res.ev.Gone)
+
+	// larger than the cap
+	fp = filepath.Join(rootDirectory, "large.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("MORE THAN SIXTEEN BYTES"), 0644))
+	res = next(fp)
+	require.True(res.nilR)
+	require.False(res.ev.Gone)
+
+	// gone by the time it is read
+	fp = filepath.Join(rootDirectory, "gone.txt")
+	require.NoError(watcher.Emit(Event{Name: fp, Op: Write}))
+	res = next(fp)
+	require.True(res.nilR)
+	require.True(res.ev.Gone)
+}
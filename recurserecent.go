@@ -0,0 +1,29 @@
+package dirwatch
+
+import (
+	"os"
+	"time"
+)
+
+// RecurseOnlyRecent limits the walk of recursive roots to the directories
+// modified within d, e.g. to watch only the active parts of a large
+// archival tree. A directory found older is skipped along with its
+// sub-tree; it gets watched once an event is reported for it in its parent,
+// like a touch. It is a heuristic: the modification time of a directory
+// only changes when entries are added to, removed from or renamed in it,
+// not when its files are written or when its sub-directories change, and
+// some file systems record it with a coarse granularity.
+func RecurseOnlyRecent(d time.Duration) Option {
+	return func(opt *options) {
+		opt.recurseRecent = d
+	}
+}
+
+// cold reports whether the directory described by inf is skipped by
+// RecurseOnlyRecent.
+func (dw *Watcher) cold(inf os.FileInfo) bool {
+	if dw.recurseRecent <= 0 {
+		return false
+	}
+	return dw.clock.Now().Sub(inf.ModTime()) > dw.recurseRecent
+}
@@ -0,0 +1,51 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurseOnlyRecent(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-recent")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	hot := filepath.Join(rootDirectory, "hot")
+	cold := filepath.Join(rootDirectory, "cold")
+	inner := filepath.Join(cold, "inner")
+	require.NoError(os.MkdirAll(hot, 0777))
+	require.NoError(os.MkdirAll(inner, 0777))
+	past := time.Now().Add(-time.Hour * 24 * 30)
+	require.NoError(os.Chtimes(inner, past, past))
+	require.NoError(os.Chtimes(cold, past, past))
+
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		RecurseOnlyRecent(time.Hour*24),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	require.True(fake.watched(rootDirectory))
+	require.True(fake.watched(hot))
+	require.False(fake.watched(cold))
+	require.False(fake.watched(inner))
+
+	// activity on the cold directory gets it watched
+	now := time.Now()
+	require.NoError(os.Chtimes(cold, now, now))
+	fake.events <- fsnotify.Event{Name: cold, Op: fsnotify.Chmod}
+	<-time.After(time.Millisecond * 100)
+	require.True(fake.watched(cold))
+}
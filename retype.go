@@ -0,0 +1,26 @@
+package dirwatch
+
+import "github.com/pkg/errors"
+
+// retype handles a watched path that has been replaced by a path of the
+// other kind, a directory by a file or a file by a directory. The watches
+// under it are dropped, and a root gets registered again, recursing into
+// it if it was added recursively. The change is reported on the errors
+// channel. It runs on the agent goroutine.
+func (dw *Watcher) retype(name string, wp watchedPath) {
+	what := "directory became a file"
+	if !wp.dir {
+		what = "file became a directory"
+	}
+	dw.fail(errors.Errorf("watched %s: %s", what, name))
+
+	for p := range dw.paths {
+		if isUnder(p, name) {
+			dw.unregisterPath(p)
+		}
+	}
+	if root, ok := dw.roots[name]; ok {
+		recursive := root.recursive
+		dw.adds.push(fspath{path: name, recursive: &recursive, tag: root.tag})
+	}
+}
@@ -0,0 +1,80 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetype(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-retype")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	sub := filepath.Join(rootDirectory, "sub")
+	require.NoError(os.MkdirAll(filepath.Join(sub, "nested"), 0777))
+	other, err := ioutil.TempDir(os.TempDir(), "dirwatch-retype")
+	require.NoError(err)
+	defer os.RemoveAll(other)
+	file := filepath.Join(other, "file")
+	require.NoError(ioutil.WriteFile(file, []byte("DATA"), 0666))
+
+	// a fake backend, to deliver the events of a replacement only after
+	// the replacement is done.
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		Logger(func(...interface{}) {}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	watcher.Add(file, true)
+	<-time.After(time.Millisecond * 100)
+	require.True(fake.watched(filepath.Join(sub, "nested")))
+
+	// a watched directory becomes a file
+	require.NoError(os.RemoveAll(sub))
+	require.NoError(ioutil.WriteFile(sub, []byte("DATA"), 0666))
+	fake.events <- fsnotify.Event{Name: sub, Op: fsnotify.Create}
+
+	select {
+	case err := <-watcher.Errors():
+		require.Contains(err.Error(), "directory became a file")
+	case <-time.After(time.Second):
+		require.Fail("no error reported")
+	}
+
+	// a watched file becomes a directory
+	require.NoError(os.Remove(file))
+	require.NoError(os.MkdirAll(filepath.Join(file, "nested"), 0777))
+	fake.events <- fsnotify.Event{Name: file, Op: fsnotify.Create}
+
+	select {
+	case err := <-watcher.Errors():
+		require.Contains(err.Error(), "file became a directory")
+	case <-time.After(time.Second):
+		require.Fail("no error reported")
+	}
+	<-time.After(time.Millisecond * 100)
+	require.True(fake.watched(filepath.Join(file, "nested")))
+
+	var paths map[string]watchedPath
+	require.NoError(watcher.call(func(backend) {
+		paths = make(map[string]watchedPath)
+		for p, wp := range watcher.paths {
+			paths[p] = wp
+		}
+	}))
+	require.NotContains(paths, sub)
+	require.NotContains(paths, filepath.Join(sub, "nested"))
+	require.True(paths[file].dir)
+	require.True(paths[filepath.Join(file, "nested")].dir)
+}
@@ -0,0 +1,56 @@
+package dirwatch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dc0d/retry"
+)
+
+// NotifyRootChanges calls fn every interval, once for each root that had
+// changes during that interval, with the number of events under it. It is
+// meant for consumers that only care whether something under a root has
+// changed, e.g. to trigger a rebuild.
+func NotifyRootChanges(interval time.Duration, fn func(root string, count int)) Option {
+	return func(opt *options) {
+		opt.rootInterval = interval
+		opt.rootFn = fn
+	}
+}
+
+type rootChanges struct {
+	mx     sync.Mutex
+	counts map[string]int
+}
+
+func (dw *Watcher) countRootChange(ev Event) {
+	if dw.rootFn == nil || ev.Root == "" {
+		return
+	}
+	dw.rootChanges.mx.Lock()
+	defer dw.rootChanges.mx.Unlock()
+	dw.rootChanges.counts[ev.Root]++
+}
+
+func (dw *Watcher) flushRootChanges() {
+	flush := dw.clock.NewTimer(dw.rootInterval)
+	defer flush.Stop()
+	for {
+		select {
+		case <-dw.stopped():
+			return
+		case <-flush.C():
+			flush.Reset(dw.rootInterval)
+		}
+
+		dw.rootChanges.mx.Lock()
+		counts := dw.rootChanges.counts
+		dw.rootChanges.counts = make(map[string]int)
+		dw.rootChanges.mx.Unlock()
+
+		for root, count := range counts {
+			root, count := root, count
+			retry.Try(func() error { dw.rootFn(root, count); return nil })
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyRootChanges(t *testing.T) {
+	require := require.New(t)
+
+	root1, err := ioutil.TempDir(os.TempDir(), "dirwatch-root-changes")
+	require.NoError(err)
+	defer os.RemoveAll(root1)
+	root2, err := ioutil.TempDir(os.TempDir(), "dirwatch-root-changes")
+	require.NoError(err)
+	defer os.RemoveAll(root2)
+
+	changes := make(chan map[string]int, 100)
+	var pending = make(map[string]int)
+	watcher := New(
+		Notify(func(Event) {}),
+		NotifyRootChanges(time.Millisecond*300, func(root string, count int) {
+			changes <- map[string]int{root: count}
+		}))
+	defer watcher.Stop()
+
+	watcher.Add(root1, true)
+	watcher.Add(root2, true)
+	<-time.After(time.Millisecond * 50)
+
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(ioutil.WriteFile(filepath.Join(root1, name), []byte("x"), 0666))
+	}
+	require.NoError(ioutil.WriteFile(filepath.Join(root2, "a"), []byte("x"), 0666))
+
+T1:
+	for {
+		select {
+		case c := <-changes:
+			for root, count := range c {
+				pending[root] += count
+			}
+		case <-time.After(time.Millisecond * 700):
+			break T1
+		}
+	}
+	require.Len(pending, 2)
+	// each file is at least created, and possibly written separately.
+	require.True(pending[root1] >= 3)
+	require.True(pending[root2] >= 1)
+}
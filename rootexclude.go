@@ -0,0 +1,78 @@
+package dirwatch
+
+import (
+	"path/filepath"
+	"sync/atomic"
+)
+
+// ExcludePerRoot adds patterns to exclude from watch under root only, for
+// roots with ignore rules of their own, like the projects of a polyrepo.
+// They are matched like the relative patterns of Exclude, against the path
+// relative to root. root does not have to be added yet.
+func ExcludePerRoot(root string, patterns ...string) Option {
+	return func(opt *options) {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		if opt.rootExclude == nil {
+			opt.rootExclude = make(map[string][]string)
+		}
+		opt.rootExclude[root] = append(opt.rootExclude[root], patterns...)
+	}
+}
+
+// rootList holds the added roots, for matching the relative exclude
+// patterns off the agent goroutine.
+type rootList struct {
+	v atomic.Value
+}
+
+func (l *rootList) load() []string {
+	roots, _ := l.v.Load().([]string)
+	return roots
+}
+
+// listRoots updates the roots for matching after they change. It runs on
+// the agent goroutine.
+func (dw *Watcher) listRoots() {
+	roots := make([]string, 0, len(dw.roots))
+	for root := range dw.roots {
+		roots = append(roots, root)
+	}
+	dw.rootList.v.Store(roots)
+}
+
+// matchRelative returns the first exclude pattern, general or specific to
+// the root, that p matches relative to one of the roots containing it.
+func (dw *Watcher) matchRelative(p string) (string, bool) {
+	// the patterns are validated on construction
+	for _, root := range dw.rootList.load() {
+		rel, ok := relativeTo(root, p)
+		if !ok {
+			continue
+		}
+		if ptrn, ok, _ := firstMatch(dw.exclude, rel); ok {
+			return ptrn, true
+		}
+	}
+	for root, patterns := range dw.rootExclude {
+		rel, ok := relativeTo(root, p)
+		if !ok {
+			continue
+		}
+		if ptrn, ok, _ := firstMatch(patterns, rel); ok {
+			return ptrn, true
+		}
+	}
+	return "", false
+}
+
+// relativeTo returns p relative to root, if p lies under root; root itself
+// is never matched.
+func relativeTo(root, p string) (string, bool) {
+	if p == root || !isUnder(p, root) {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, p)
+	return rel, err == nil
+}
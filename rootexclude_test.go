@@ -0,0 +1,91 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcludePerRoot(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-rootexclude")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	web := filepath.Join(rootDirectory, "web")
+	api := filepath.Join(rootDirectory, "api")
+	for _, dir := range []string{
+		filepath.Join(web, "dist"),
+		filepath.Join(web, "target"),
+		filepath.Join(web, "build", "out"),
+		filepath.Join(api, "dist"),
+		filepath.Join(api, "target"),
+		filepath.Join(api, "build", "out"),
+	} {
+		require.NoError(os.MkdirAll(dir, 0755))
+	}
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		Exclude("*/out"),
+		ExcludePerRoot(web, "dist"),
+		ExcludePerRoot(api, "target"))
+	defer watcher.Stop()
+	require.NoError(watcher.AddContext(context.Background(), web, true))
+	require.NoError(watcher.AddContext(context.Background(), api, true))
+	<-time.After(time.Millisecond * 100)
+
+	var ignored, kept []string
+	for _, dir := range []string{
+		filepath.Join(web, "dist"),
+		filepath.Join(web, "build", "out"),
+		filepath.Join(api, "target"),
+		filepath.Join(api, "build", "out"),
+	} {
+		ignored = append(ignored, filepath.Join(dir, "ignored.txt"))
+	}
+	for _, dir := range []string{
+		filepath.Join(web, "target"),
+		filepath.Join(web, "build"),
+		filepath.Join(api, "dist"),
+		filepath.Join(api, "build"),
+	} {
+		kept = append(kept, filepath.Join(dir, "kept.txt"))
+	}
+	for _, fp := range append(ignored, kept...) {
+		require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	}
+
+	seen := make(map[string]bool)
+	for len(seen) < len(kept) {
+		select {
+		case ev := <-events:
+			seen[ev.Name] = true
+		case <-time.After(time.Second * 5):
+			require.FailNow("events not received", "%v", seen)
+		}
+	}
+	<-time.After(time.Millisecond * 200)
+	for len(events) > 0 {
+		ev := <-events
+		seen[ev.Name] = true
+	}
+	for _, fp := range kept {
+		require.True(seen[fp], fp)
+	}
+	for _, fp := range ignored {
+		require.False(seen[fp], fp)
+	}
+
+	ok, reason := watcher.WouldWatch(filepath.Join(web, "dist"))
+	require.False(ok)
+	require.Contains(reason, "dist")
+	ok, _ = watcher.WouldWatch(filepath.Join(api, "dist"))
+	require.True(ok)
+}
@@ -0,0 +1,157 @@
+// Package run runs a command each time the files watched by a
+// dirwatch.Watcher change, like entr or air.
+package run
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/dc0d/dirwatch"
+	"github.com/pkg/errors"
+)
+
+// Option sets an option of a Runner.
+type Option func(*options)
+
+type options struct {
+	stdout, stderr io.Writer
+	onExit         func(code int, err error)
+}
+
+// Output sets where the output of the command goes; os.Stdout and
+// os.Stderr by default.
+func Output(stdout, stderr io.Writer) Option {
+	return func(opt *options) {
+		opt.stdout = stdout
+		opt.stderr = stderr
+	}
+}
+
+// OnExit sets a function that is called with the exit code of each run of
+// the command, and the error of running it, if any. A run that is killed
+// to make room for a new one reports a code of -1.
+func OnExit(fn func(code int, err error)) Option {
+	return func(opt *options) {
+		opt.onExit = fn
+	}
+}
+
+// Runner runs a command after the events of a watcher settle.
+type Runner struct {
+	options
+	cmd      []string
+	debounce time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// OnChangeRun runs cmd once the events of watcher have settled for
+// debounce. If the previous run is still going when the command is due to
+// run again, it is killed first, so runs never overlap. Events the watcher
+// excludes do not trigger a run. An empty cmd is an error.
+func OnChangeRun(watcher *dirwatch.Watcher, cmd []string, debounce time.Duration, opt ...Option) (*Runner, error) {
+	if len(cmd) == 0 {
+		return nil, errors.New("cmd can not be empty")
+	}
+	r := &Runner{
+		options: options{
+			stdout: os.Stdout,
+			stderr: os.Stderr,
+			onExit: func(int, error) {},
+		},
+		cmd:      cmd,
+		debounce: debounce,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, v := range opt {
+		v(&r.options)
+	}
+	events, cancel := watcher.Subscribe(1)
+	go func() {
+		defer close(r.done)
+		defer cancel()
+		r.loop(events)
+	}()
+	return r, nil
+}
+
+// Stop stops the runner, killing the command if it is running, and waits
+// for it to exit.
+func (r *Runner) Stop() {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	<-r.done
+}
+
+type exit struct {
+	code int
+	err  error
+}
+
+func (r *Runner) loop(events <-chan dirwatch.Event) {
+	timer := time.NewTimer(r.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	var (
+		running *exec.Cmd
+		exited  chan exit
+	)
+	kill := func() {
+		if running == nil {
+			return
+		}
+		select {
+		case res := <-exited:
+			// it exited on its own before it got killed
+			r.onExit(res.code, res.err)
+		default:
+			killed := running.Process.Kill() == nil
+			res := <-exited
+			if killed {
+				res.code = -1
+			}
+			r.onExit(res.code, res.err)
+		}
+		running = nil
+		exited = nil
+	}
+	defer kill()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			timer.Reset(r.debounce)
+		case <-timer.C:
+			kill()
+			cmd := exec.Command(r.cmd[0], r.cmd[1:]...)
+			cmd.Stdout = r.stdout
+			cmd.Stderr = r.stderr
+			if err := cmd.Start(); err != nil {
+				r.onExit(-1, err)
+				continue
+			}
+			running = cmd
+			exited = make(chan exit, 1)
+			go func(exited chan exit) {
+				err := cmd.Wait()
+				exited <- exit{code: cmd.ProcessState.ExitCode(), err: err}
+			}(exited)
+		case res := <-exited:
+			r.onExit(res.code, res.err)
+			running = nil
+			exited = nil
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dc0d/dirwatch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnChangeRun(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-run")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	outDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-run-out")
+	require.NoError(err)
+	defer os.RemoveAll(outDirectory)
+	out := filepath.Join(outDirectory, "runs")
+
+	watcher := dirwatch.New(dirwatch.Notify(func(dirwatch.Event) {}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+
+	exits := make(chan int, 10)
+	_, err = OnChangeRun(watcher, nil, time.Millisecond*100)
+	require.Error(err)
+
+	runner, err := OnChangeRun(watcher,
+		[]string{"sh", "-c", "echo run >> " + out + "; exit 3"},
+		time.Millisecond*100,
+		Output(ioutil.Discard, ioutil.Discard),
+		OnExit(func(code int, err error) { exits <- code }))
+	require.NoError(err)
+	defer runner.Stop()
+
+	burst := func() {
+		for i := 0; i < 5; i++ {
+			require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "text.txt"), []byte("DATA"), 0666))
+			<-time.After(time.Millisecond * 10)
+		}
+	}
+
+	burst()
+	select {
+	case code := <-exits:
+		require.Equal(3, code)
+	case <-time.After(time.Second * 2):
+		require.Fail("command did not run")
+	}
+	burst()
+	select {
+	case code := <-exits:
+		require.Equal(3, code)
+	case <-time.After(time.Second * 2):
+		require.Fail("command did not run")
+	}
+	<-time.After(time.Millisecond * 200)
+
+	data, err := ioutil.ReadFile(out)
+	require.NoError(err)
+	require.Equal("run\nrun\n", string(data))
+}
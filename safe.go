@@ -0,0 +1,10 @@
+package dirwatch
+
+// SafeDefaults excludes the virtual file systems of the platform, as listed
+// by PresetVirtualFS, so that recursively watching / does not try to watch
+// the millions of entries of e.g. /proc and run out of watches. It is not
+// on by default; not using it opts out. A path under one of them can still
+// be watched when it is added explicitly.
+func SafeDefaults() Option {
+	return ExcludePresets(PresetVirtualFS)
+}
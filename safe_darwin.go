@@ -0,0 +1,5 @@
+package dirwatch
+
+// PresetVirtualFS lists the virtual file systems excluded by SafeDefaults.
+// On macOS: /dev, /.vol (the volfs namespace) and /private/var/vm (swap).
+var PresetVirtualFS = PresetSet{"/dev", "/.vol", "/private/var/vm"}
@@ -0,0 +1,5 @@
+package dirwatch
+
+// PresetVirtualFS lists the virtual file systems excluded by SafeDefaults.
+// On Linux: /proc, /sys, /dev and /run.
+var PresetVirtualFS = PresetSet{"/proc", "/sys", "/dev", "/run"}
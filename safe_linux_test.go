@@ -0,0 +1,31 @@
+package dirwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeDefaults(t *testing.T) {
+	require := require.New(t)
+
+	watcher := New(Notify(func(Event) {}), SafeDefaults())
+	defer watcher.Stop()
+
+	// the first level of a recursive walk of /
+	dirs, err := watcher.subDirs("/")
+	require.NoError(err)
+	require.NotContains(dirs, "/proc")
+	require.NotContains(dirs, "/sys")
+	require.Contains(dirs, "/tmp")
+
+	ok, reason := watcher.WouldWatch("/proc")
+	require.False(ok)
+	require.Equal("excluded by pattern /proc", reason)
+
+	unsafe := New(Notify(func(Event) {}))
+	defer unsafe.Stop()
+	dirs, err = unsafe.subDirs("/")
+	require.NoError(err)
+	require.Contains(dirs, "/proc")
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package dirwatch
+
+// PresetVirtualFS lists the virtual file systems excluded by SafeDefaults.
+// On the BSDs: /dev and /proc. On Windows, where they do not exist, it has
+// no effect.
+var PresetVirtualFS = PresetSet{"/dev", "/proc"}
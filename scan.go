@@ -0,0 +1,76 @@
+package dirwatch
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ScanExisting makes adding a root emit a synthetic Create event for every
+// path already under it, the root itself excluded. The tree is walked
+// depth-first in lexical order, so a directory is always reported before
+// its contents, and the events are delivered in that order. As the walk
+// runs in the background, the events of changes made meanwhile may come
+// before the synthetic ones. It implies OrderedDelivery.
+func ScanExisting() Option {
+	return func(opt *options) {
+		opt.scanExisting = true
+		opt.ordered = true
+	}
+}
+
+// scan emits the synthetic Create events of ScanExisting for root, passing
+// them through the filters of the real events. The tree is walked on a
+// goroutine of its own, or on the agent goroutine with StrictSingleAgent,
+// and the events are passed to the agent in batches. It runs on the agent
+// goroutine.
+func (dw *Watcher) scan(root string, recursive bool) {
+	type scanned struct {
+		ev  Event
+		inf os.FileInfo
+	}
+	var batch []scanned
+	flush := func() error {
+		events := batch
+		batch = nil
+		pass := func() {
+			for _, s := range events {
+				dw.filterEvent(dw.attribute(s.ev), s.inf, s.inf.IsDir())
+			}
+		}
+		if dw.singleAgent {
+			pass()
+			return nil
+		}
+		return dw.call(func(backend) { pass() })
+	}
+	walk := func() {
+		err := dw.walk(root, func(path string, f os.FileInfo) error {
+			if path == root {
+				return nil
+			}
+			if !dw.isSpecial(f) {
+				batch = append(batch, scanned{Event{Name: path, Op: Create, Time: dw.clock.Now()}, f})
+				if len(batch) == walkBatch {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+			if f.IsDir() && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err != nil && err != ErrWatcherStopped {
+			dw.fail(err)
+		}
+	}
+	if dw.singleAgent {
+		walk()
+		return
+	}
+	dw.budget.spawn(walk)
+}
@@ -0,0 +1,86 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanExisting(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-scan")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "a", "b"), 0777))
+	for _, name := range []string{"a/x.txt", "a/b/y.txt", "c.txt"} {
+		require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, name), []byte("DATA"), 0666))
+	}
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) {
+		// a slow consumer must not change the order
+		<-time.After(time.Millisecond * 10)
+		events <- ev
+	}), ScanExisting())
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+
+	var creates []string
+T1:
+	for {
+		select {
+		case ev := <-events:
+			require.Equal(Create, ev.Op)
+			require.Equal(rootDirectory, ev.Root)
+			rel, err := filepath.Rel(rootDirectory, ev.Name)
+			require.NoError(err)
+			creates = append(creates, filepath.ToSlash(rel))
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal([]string{"a", "a/b", "a/b/y.txt", "a/x.txt", "c.txt"}, creates)
+}
+
+func TestScanExistingFiltered(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-scan")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "a"), 0777))
+	for _, name := range []string{"a/x.txt", "a/x.log", "c.txt"} {
+		require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, name), []byte("DATA"), 0666))
+	}
+
+	events := make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		ScanExisting(),
+		ExcludeFunc(func(ev Event) bool { return filepath.Ext(ev.Name) == ".log" }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+
+	var creates []string
+T1:
+	for {
+		select {
+		case ev := <-events:
+			rel, err := filepath.Rel(rootDirectory, ev.Name)
+			require.NoError(err)
+			creates = append(creates, filepath.ToSlash(rel))
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal([]string{"a", "a/x.txt", "c.txt"}, creates)
+}
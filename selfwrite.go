@@ -0,0 +1,89 @@
+package dirwatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// selfWriteTTL is how long a change made by the watcher itself is waited
+// for, to be filtered out of the events.
+const selfWriteTTL = time.Second * 2
+
+// CreateIfMissing makes the watcher create the added paths that do not
+// exist, as directories.
+func CreateIfMissing() Option {
+	return func(opt *options) {
+		opt.createMissing = true
+	}
+}
+
+// SelfWritesSuppressed sets whether the events caused by the changes the
+// watcher makes itself to the file system, like creating directories for
+// CreateIfMissing, are filtered out. It is on by default.
+func SelfWritesSuppressed(suppress bool) Option {
+	return func(opt *options) {
+		opt.selfWrites = suppress
+	}
+}
+
+type selfWrite struct {
+	path string
+	op   Op
+}
+
+type selfWrites struct {
+	mx      sync.Mutex
+	pending map[selfWrite]time.Time
+}
+
+// recordSelfWrite marks the upcoming op on path as caused by the watcher.
+func (dw *Watcher) recordSelfWrite(path string, op Op) {
+	dw.self.mx.Lock()
+	defer dw.self.mx.Unlock()
+	dw.self.pending[selfWrite{path: path, op: op}] = dw.clock.Now().Add(selfWriteTTL)
+}
+
+// isSelfWrite reports whether ev was caused by the watcher, consuming the
+// record of that change.
+func (dw *Watcher) isSelfWrite(ev Event) bool {
+	if !dw.selfWrites {
+		return false
+	}
+	dw.self.mx.Lock()
+	defer dw.self.mx.Unlock()
+	now := dw.clock.Now()
+	for k, expires := range dw.self.pending {
+		if now.After(expires) {
+			delete(dw.self.pending, k)
+		}
+	}
+	k := selfWrite{path: ev.Name, op: ev.Op}
+	if _, ok := dw.self.pending[k]; !ok {
+		return false
+	}
+	delete(dw.self.pending, k)
+	return true
+}
+
+// makeMissing creates path and its missing parents as directories.
+func (dw *Watcher) makeMissing(path string) error {
+	var missing []string
+	for p := path; ; p = filepath.Dir(p) {
+		if _, err := os.Stat(p); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, p)
+		if p == filepath.Dir(p) {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		dw.recordSelfWrite(missing[i], Create)
+		if err := os.Mkdir(missing[i], 0777); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
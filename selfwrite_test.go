@@ -0,0 +1,58 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfWritesSuppressed(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-self")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	collect := func(events chan Event) (names []string) {
+		for {
+			select {
+			case ev := <-events:
+				names = append(names, ev.Name)
+			case <-time.After(time.Millisecond * 300):
+				return
+			}
+		}
+	}
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), CreateIfMissing())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	child := filepath.Join(rootDirectory, "child")
+	watcher.Add(child, false)
+	<-time.After(time.Millisecond * 50)
+	fp := filepath.Join(child, "text.txt")
+	require.NoError(ioutil.WriteFile(fp, nil, 0777))
+	require.Equal([]string{fp}, collect(events))
+
+	// without suppression, the creation of the directory is reported
+	var others = make(chan Event, 100)
+	other := New(
+		Notify(func(ev Event) { others <- ev }),
+		CreateIfMissing(),
+		SelfWritesSuppressed(false))
+	defer other.Stop()
+	other.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	child = filepath.Join(rootDirectory, "other")
+	other.Add(child, false)
+	<-time.After(time.Millisecond * 50)
+	require.Equal([]string{child}, collect(others))
+}
@@ -0,0 +1,24 @@
+package dirwatch
+
+// SetNotify replaces the notify callback, e.g. when the application
+// changes modes, without stopping the watcher. Events delivered after it
+// returns go to notify, including the ones queued before; the callbacks
+// already running on the old one are left to complete. It replaces a
+// NotifyErr or NotifyContext callback too, but has no effect with
+// NotifyBatch.
+func (dw *Watcher) SetNotify(notify func(Event)) {
+	dw.setNotify(func(ev Event) error {
+		notify(ev)
+		return nil
+	})
+}
+
+func (dw *Watcher) setNotify(notify func(Event) error) {
+	dw.notifyFn.Store(notify)
+}
+
+// currentNotify returns the notify callback events are delivered to.
+func (dw *Watcher) currentNotify() func(Event) error {
+	notify, _ := dw.notifyFn.Load().(func(Event) error)
+	return notify
+}
@@ -0,0 +1,63 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetNotify(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-setnotify")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	first := filepath.Join(rootDirectory, "first.txt")
+	second := filepath.Join(rootDirectory, "second.txt")
+	require.NoError(ioutil.WriteFile(first, []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(second, []byte("DATA"), 0644))
+
+	fake := newFakeBackend()
+	var (
+		indexing = make(chan Event, 100)
+		serving  = make(chan Event, 100)
+		started  = make(chan struct{})
+		release  = make(chan struct{})
+	)
+	watcher := New(
+		Notify(func(ev Event) {
+			close(started)
+			<-release
+			indexing <- ev
+		}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	fake.events <- fsnotify.Event{Name: first, Op: fsnotify.Write}
+	<-started
+	watcher.SetNotify(func(ev Event) { serving <- ev })
+	fake.events <- fsnotify.Event{Name: second, Op: fsnotify.Write}
+
+	select {
+	case ev := <-serving:
+		require.Equal(second, ev.Name)
+	case <-time.After(time.Second * 5):
+		require.FailNow("new callback was not called")
+	}
+
+	// the running call of the old callback completes
+	close(release)
+	select {
+	case ev := <-indexing:
+		require.Equal(first, ev.Name)
+	case <-time.After(time.Second * 5):
+		require.FailNow("old callback did not complete")
+	}
+	require.Len(indexing, 0)
+	require.Len(serving, 0)
+}
@@ -0,0 +1,60 @@
+package dirwatch
+
+import "time"
+
+// SettleAfterAdd holds back the events under a newly added root for d after
+// it gets registered, and delivers them once d has passed, in order. It
+// gives consumers a clean baseline when a directory is added while it is
+// still being written to. The tradeoff is that real changes made during
+// that window are delivered late, by up to d. Unlike ScanExisting, it does
+// not report what already exists. Adding a root again does not start a new
+// window.
+func SettleAfterAdd(d time.Duration) Option {
+	return func(opt *options) {
+		opt.settle = d
+	}
+}
+
+type settleWindow struct {
+	held []heldEvent
+}
+
+// startSettle opens the settle window of root. It must be called from the
+// agent goroutine.
+func (dw *Watcher) startSettle(root string) {
+	if dw.settle <= 0 {
+		return
+	}
+	if _, ok := dw.settling[root]; ok {
+		return
+	}
+	w := &settleWindow{}
+	dw.settling[root] = w
+	dw.clock.AfterFunc(dw.settle, func() {
+		dw.call(func(backend) { dw.endSettle(root, w) })
+	})
+}
+
+// holdSettling reports whether ev belongs to a root that is still settling,
+// holding it back if so. It must be called from the agent goroutine.
+func (dw *Watcher) holdSettling(ev Event, isdir bool) bool {
+	w, ok := dw.settling[ev.Root]
+	if !ok {
+		return false
+	}
+	w.held = append(w.held, heldEvent{ev: ev, isdir: isdir})
+	return true
+}
+
+// endSettle closes the settle window w of root and delivers the events it
+// held. It must be called from the agent goroutine.
+func (dw *Watcher) endSettle(root string, w *settleWindow) {
+	if dw.settling[root] != w {
+		return
+	}
+	delete(dw.settling, root)
+	for _, h := range w.held {
+		dw.trace(h.ev.Name, "delivered", h.ev.Op)
+		dw.pass(h.ev, h.isdir)
+	}
+}
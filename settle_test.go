@@ -0,0 +1,111 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettleAfterAdd(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-settle")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	clock := newFakeClock()
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		SettleAfterAdd(time.Minute),
+		withClock(clock))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	clock.waitTimers(1)
+
+	fp := filepath.Join(rootDirectory, "during.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+
+	select {
+	case ev := <-events:
+		require.FailNow("delivered during the settle window", ev.Name)
+	case <-time.After(time.Millisecond * 300):
+	}
+	// held events are not filtered
+	watcher.call(func(backend) {})
+	require.Zero(watcher.Stats().Filtered)
+
+	clock.Advance(time.Minute)
+	select {
+	case ev := <-events:
+		require.Equal(fp, ev.Name)
+		require.Equal(rootDirectory, ev.Root)
+	case <-time.After(time.Second * 5):
+		require.FailNow("held events were not delivered")
+	}
+
+	// once settled, events are delivered right away
+	fp = filepath.Join(rootDirectory, "after.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	for {
+		select {
+		case ev := <-events:
+			if ev.Name == fp {
+				return
+			}
+		case <-time.After(time.Second * 5):
+			require.FailNow("event after the settle window was not delivered")
+		}
+	}
+}
+
+func TestSettleAfterAddDebounced(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-settle")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	clock := newFakeClock()
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		SettleAfterAdd(time.Minute),
+		PerFileDebounce(time.Second),
+		withClock(clock))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	clock.waitTimers(1)
+
+	fp := filepath.Join(rootDirectory, "during.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(fp, []byte("MORE DATA"), 0644))
+	<-time.After(time.Millisecond * 300)
+
+	// the held events are debounced like any other
+	clock.Advance(time.Minute)
+	select {
+	case ev := <-events:
+		require.FailNow("held events were not debounced", ev.Name)
+	case <-time.After(time.Millisecond * 300):
+	}
+	clock.waitTimers(2)
+	clock.Advance(time.Second)
+	select {
+	case ev := <-events:
+		require.Equal(fp, ev.Name)
+	case <-time.After(time.Second * 5):
+		require.FailNow("held events were not delivered")
+	}
+	select {
+	case ev := <-events:
+		require.FailNow("held events were delivered more than once", ev.Name)
+	case <-time.After(time.Millisecond * 300):
+	}
+}
@@ -0,0 +1,32 @@
+package dirwatch
+
+// StrictSingleAgent does all the work of the watcher on its agent
+// goroutine, for consumers who value a deterministic order over
+// throughput. The notify callback is called synchronously, in the order
+// the events were observed, so a slow callback holds up the watcher, and
+// must not call the methods that wait for it, like Add; with
+// OrderedDelivery, it is called from a single goroutine of its own instead,
+// still in order. Recursive adds are walked one directory at a time,
+// taking turns with the events, instead of on walking goroutines, so
+// WalkTimeout and MaxGoroutines have nothing to bound.
+func StrictSingleAgent() Option {
+	return func(opt *options) {
+		opt.singleAgent = true
+	}
+}
+
+// descend queues the sub-directories of fsp, to be added and descended
+// into in turn, for StrictSingleAgent. It runs on the agent goroutine.
+func (dw *Watcher) descend(fsp fspath) {
+	dirs, err := dw.subDirs(fsp.path)
+	if err != nil {
+		dw.fail(err)
+		return
+	}
+	batch := make([]fspath, len(dirs))
+	for i, dir := range dirs {
+		// a leaf directory is watched, but not descended into
+		batch[i] = fspath{path: dir, priority: fsp.priority, descend: !dw.isLeaf(dir)}
+	}
+	dw.queueAdds(batch...)
+}
@@ -0,0 +1,90 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictSingleAgentOrder(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-singleagent")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	var names []string
+	for i := 0; i < 50; i++ {
+		fp := filepath.Join(rootDirectory, fmt.Sprintf("%02d.txt", i))
+		require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+		names = append(names, fp)
+	}
+
+	fake := newFakeBackend()
+	var events = make(chan Event, 1000)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		StrictSingleAgent(),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	var want []string
+	for round := 0; round < 4; round++ {
+		for _, fp := range names {
+			fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+			want = append(want, fp)
+		}
+	}
+
+	var got []string
+	for len(got) < len(want) {
+		select {
+		case ev := <-events:
+			got = append(got, ev.Name)
+		case <-time.After(time.Second * 5):
+			require.FailNow("events were not delivered", "%d of %d", len(got), len(want))
+		}
+	}
+	require.Equal(want, got)
+	require.Equal(0, watcher.Stats().Goroutines)
+}
+
+func TestStrictSingleAgentWalk(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-singleagent")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 3, 3)
+
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		StrictSingleAgent(),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+
+	var dirs []string
+	require.NoError(filepath.Walk(rootDirectory, func(p string, inf os.FileInfo, err error) error {
+		if inf.IsDir() {
+			dirs = append(dirs, p)
+		}
+		return err
+	}))
+	require.Len(dirs, 1+3+9+27)
+	deadline := time.Now().Add(time.Second * 5)
+	for _, dir := range dirs {
+		for !fake.watched(dir) {
+			require.True(time.Now().Before(deadline), "not watched: %s", dir)
+			time.Sleep(time.Millisecond * 10)
+		}
+		require.Equal(1, fake.addCount(dir))
+	}
+	require.Equal(0, watcher.Stats().Goroutines)
+}
@@ -0,0 +1,45 @@
+package dirwatch
+
+import (
+	"io"
+	"net/http"
+	"os"
+)
+
+// SniffContentType sets Event.ContentType of Create and Write events of
+// regular files, as detected by http.DetectContentType from their first
+// 512 bytes, so consumers do not need to open them to route them. The files
+// are read off the watcher goroutine; one that can not be read, or is
+// empty, gets no ContentType. Like ContentHashGate, it makes subscribers
+// receive events in no particular order.
+func SniffContentType() Option {
+	return func(opt *options) {
+		opt.sniff = true
+	}
+}
+
+// sniffLen is the most http.DetectContentType looks at.
+const sniffLen = 512
+
+// sniffContent sets the ContentType of ev, as set by SniffContentType.
+func (dw *Watcher) sniffContent(ev Event) Event {
+	if !dw.sniff || ev.Op&(Create|Write) == 0 {
+		return ev
+	}
+	f, err := os.Open(dw.physical(ev))
+	if err != nil {
+		return ev
+	}
+	defer f.Close()
+	inf, err := f.Stat()
+	if err != nil || !inf.Mode().IsRegular() {
+		return ev
+	}
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if n == 0 || (err != nil && err != io.ErrUnexpectedEOF) {
+		return ev
+	}
+	ev.ContentType = http.DetectContentType(buf[:n])
+	return ev
+}
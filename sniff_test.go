@@ -0,0 +1,49 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffContentType(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-sniff")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), SniffContentType())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 50)
+
+	png := filepath.Join(rootDirectory, "image.png")
+	text := filepath.Join(rootDirectory, "notes.txt")
+	dir := filepath.Join(rootDirectory, "dir")
+	require.NoError(ioutil.WriteFile(png, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644))
+	require.NoError(ioutil.WriteFile(text, []byte("hello, world\n"), 0644))
+	require.NoError(os.Mkdir(dir, 0777))
+
+	types := make(map[string]string)
+T1:
+	for {
+		select {
+		case ev := <-events:
+			if ev.ContentType != "" {
+				types[ev.Name] = ev.ContentType
+			}
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(map[string]string{
+		png:  "image/png",
+		text: "text/plain; charset=utf-8",
+	}, types)
+}
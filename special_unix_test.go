@@ -0,0 +1,52 @@
+//go:build !windows
+// +build !windows
+
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipSpecialFiles(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-special")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var events = make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), SkipSpecialFiles())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 50)
+
+	fifo := filepath.Join(rootDirectory, "pipe")
+	require.NoError(syscall.Mkfifo(fifo, 0666))
+	fp := filepath.Join(rootDirectory, "text.txt")
+	require.NoError(ioutil.WriteFile(fp, nil, 0777))
+
+	var names []string
+T1:
+	for {
+		select {
+		case ev := <-events:
+			names = append(names, ev.Name)
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Contains(names, fp)
+	require.NotContains(names, fifo)
+
+	ok, reason := watcher.WouldWatch(fifo)
+	require.False(ok)
+	require.True(strings.HasPrefix(reason, "special file"))
+}
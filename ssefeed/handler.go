@@ -0,0 +1,115 @@
+// Package ssefeed serves the events of a dirwatch.Watcher to browsers as
+// Server-Sent Events, e.g. for live-reload in development servers.
+package ssefeed
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dc0d/dirwatch"
+)
+
+// Event is the JSON form of a dirwatch.Event, sent as the data of each
+// server-sent event.
+type Event struct {
+	Name    string    `json:"name"`
+	Op      string    `json:"op"`
+	Time    time.Time `json:"time"`
+	Root    string    `json:"root,omitempty"`
+	RelName string    `json:"rel_name,omitempty"`
+}
+
+// Handler streams the events of a watcher to every connected client. The
+// query parameters ext (e.g. ext=.css, repeatable) and prefix limit the
+// events a client receives to the ones with one of the extensions and under
+// the path prefix.
+type Handler struct {
+	watcher *dirwatch.Watcher
+	buffer  int
+}
+
+// NewHandler creates a Handler streaming the events of watcher. Each
+// connection buffers up to buffer events; a client that falls further
+// behind misses events.
+func NewHandler(watcher *dirwatch.Watcher, buffer int) *Handler {
+	return &Handler{watcher: watcher, buffer: buffer}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	exts := r.URL.Query()["ext"]
+	prefix := r.URL.Query().Get("prefix")
+
+	events, cancel := h.watcher.Subscribe(h.buffer)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if !matches(ev, exts, prefix) {
+				continue
+			}
+			data, err := json.Marshal(Event{
+				Name:    ev.Name,
+				Op:      ev.Op.String(),
+				Time:    ev.Time,
+				Root:    ev.Root,
+				RelName: ev.RelName,
+			})
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func matches(ev dirwatch.Event, exts []string, prefix string) bool {
+	if prefix != "" && !isUnder(ev.Name, prefix) {
+		return false
+	}
+	if len(exts) == 0 {
+		return true
+	}
+	ext := filepath.Ext(ev.Name)
+	for _, v := range exts {
+		if v == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnder reports whether p is dir or a path under it; /a/bc is not under
+// /a/b.
+func isUnder(p, dir string) bool {
+	if p == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(p, dir)
+}
@@ -0,0 +1,74 @@
+package ssefeed
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dc0d/dirwatch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-ssefeed")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	site := filepath.Join(rootDirectory, "site")
+	require.NoError(os.Mkdir(site, 0777))
+	require.NoError(os.Mkdir(filepath.Join(rootDirectory, "sitemap"), 0777))
+
+	watcher := dirwatch.New(dirwatch.Notify(func(dirwatch.Event) {}))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, true)
+
+	srv := httptest.NewServer(NewHandler(watcher, 100))
+	defer srv.Close()
+
+	q := url.Values{"ext": {".css"}, "prefix": {site}}
+	resp, err := http.Get(srv.URL + "?" + q.Encode())
+	require.NoError(err)
+	defer resp.Body.Close()
+	require.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := make(chan string, 100)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	require.Equal(": connected", <-lines)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(site, "app.js"), []byte("DATA"), 0666))
+	// the prefix matches whole path elements
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "sitemap", "map.css"), []byte("DATA"), 0666))
+	name := filepath.Join(site, "site.css")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+
+	for {
+		select {
+		case line := <-lines:
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var ev Event
+			require.NoError(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev))
+			require.Equal(name, ev.Name)
+			require.Contains(ev.Op, "CREATE")
+			return
+		case <-time.After(time.Second):
+			require.Fail("event not received")
+		}
+	}
+}
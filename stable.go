@@ -0,0 +1,67 @@
+package dirwatch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dc0d/retry"
+)
+
+// OnStable calls fn once a file has had no Write event for quiet, passing
+// the last Write event of that file. Unlike notify, it fires once per
+// burst of writes, e.g. when a download has finished. If the file gets
+// removed or renamed before it stabilizes, its pending call is cancelled.
+func OnStable(quiet time.Duration, fn func(Event)) Option {
+	return func(opt *options) {
+		opt.stableQuiet = quiet
+		opt.stableFn = fn
+	}
+}
+
+type stableFiles struct {
+	mx     sync.Mutex
+	timers map[string]timer
+}
+
+func (dw *Watcher) trackStable(ev Event) {
+	if dw.stableFn == nil {
+		return
+	}
+	dw.stable.mx.Lock()
+	defer dw.stable.mx.Unlock()
+
+	switch {
+	case ev.Op&(Remove|Rename) != 0:
+	case ev.Op&Write != 0:
+	default:
+		// other ops, like Chmod, leave the pending call alone
+		return
+	}
+	if t, ok := dw.stable.timers[ev.Name]; ok {
+		t.Stop()
+		delete(dw.stable.timers, ev.Name)
+	}
+	if ev.Op&Write == 0 {
+		return
+	}
+
+	var t timer
+	t = dw.clock.AfterFunc(dw.stableQuiet, func() {
+		dw.stable.mx.Lock()
+		current := dw.stable.timers[ev.Name] == t
+		if current {
+			delete(dw.stable.timers, ev.Name)
+		}
+		dw.stable.mx.Unlock()
+		if !current {
+			return
+		}
+		select {
+		case <-dw.stopped():
+			return
+		default:
+		}
+		retry.Try(func() error { dw.stableFn(ev); return nil })
+	})
+	dw.stable.timers[ev.Name] = t
+}
@@ -0,0 +1,73 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnStable(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-stable")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	var stable = make(chan Event, 100)
+	watcher := New(
+		Notify(func(Event) {}),
+		OnStable(time.Millisecond*200, func(ev Event) { stable <- ev }))
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+
+	// a download, written in chunks
+	fp := filepath.Join(rootDirectory, "download.bin")
+	f, err := os.Create(fp)
+	require.NoError(err)
+	for i := 0; i < 10; i++ {
+		_, err = f.Write([]byte("CHUNK"))
+		require.NoError(err)
+		<-time.After(time.Millisecond * 30)
+	}
+	require.NoError(f.Close())
+
+	select {
+	case ev := <-stable:
+		require.Equal(fp, ev.Name)
+		require.Equal(Write, ev.Op)
+	case <-time.After(time.Second * 5):
+		require.FailNow("file did not stabilize")
+	}
+	select {
+	case ev := <-stable:
+		require.FailNow("stabilized more than once", ev.Name)
+	case <-time.After(time.Millisecond * 400):
+	}
+
+	// a chmod does not cancel it
+	fp = filepath.Join(rootDirectory, "script.sh")
+	require.NoError(ioutil.WriteFile(fp, []byte("CHUNK"), 0644))
+	<-time.After(time.Millisecond * 50)
+	require.NoError(os.Chmod(fp, 0755))
+	select {
+	case ev := <-stable:
+		require.Equal(fp, ev.Name)
+	case <-time.After(time.Second * 5):
+		require.FailNow("chmod cancelled stabilizing")
+	}
+
+	// removed before it stabilizes
+	fp = filepath.Join(rootDirectory, "partial.bin")
+	require.NoError(ioutil.WriteFile(fp, []byte("CHUNK"), 0777))
+	<-time.After(time.Millisecond * 50)
+	require.NoError(os.Remove(fp))
+	select {
+	case ev := <-stable:
+		require.FailNow("removed file stabilized", ev.Name)
+	case <-time.After(time.Millisecond * 400):
+	}
+}
@@ -0,0 +1,101 @@
+package dirwatch
+
+import (
+	"container/list"
+	"sync"
+)
+
+// StateCapacity bounds the number of per-path entries the watcher keeps for
+// NewFilesOnly, TrackXattrs, ContentHashGate, PerFileDebounce, OnDirDelta,
+// TrackInodes and VerifyWithStat; the least recently used ones get evicted
+// first. Losing an entry is safe: at worst an event that would have been
+// dropped gets delivered, or one is not stat'ed again. The default is
+// 100000; the current size is reported in Stats.State.
+func StateCapacity(n int) Option {
+	return func(opt *options) {
+		opt.stateCapacity = n
+	}
+}
+
+const defaultStateCapacity = 100000
+
+type stateKind int
+
+const (
+	stateNewFile stateKind = iota
+	stateXattrs
+	stateHash
+	stateDebounce
+	stateListing
+	stateInode
+	stateVerify
+)
+
+type stateKey struct {
+	kind stateKind
+	path string
+}
+
+type stateEntry struct {
+	key   stateKey
+	value interface{}
+}
+
+// stateStore is an LRU store of auxiliary per-path state, shared by the
+// features that need to remember something about a path.
+type stateStore struct {
+	mx       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[stateKey]*list.Element
+}
+
+func newStateStore(capacity int) *stateStore {
+	return &stateStore{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[stateKey]*list.Element),
+	}
+}
+
+func (s *stateStore) get(key stateKey) (interface{}, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(*stateEntry).value, true
+}
+
+func (s *stateStore) set(key stateKey, value interface{}) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if e, ok := s.items[key]; ok {
+		e.Value.(*stateEntry).value = value
+		s.order.MoveToFront(e)
+		return
+	}
+	s.items[key] = s.order.PushFront(&stateEntry{key: key, value: value})
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*stateEntry).key)
+	}
+}
+
+func (s *stateStore) remove(key stateKey) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if e, ok := s.items[key]; ok {
+		s.order.Remove(e)
+		delete(s.items, key)
+	}
+}
+
+func (s *stateStore) len() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.order.Len()
+}
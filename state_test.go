@@ -0,0 +1,65 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateCapacity(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-state")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	events := make(chan Event, 1000)
+	watcher := New(Notify(func(ev Event) { events <- ev }), NewFilesOnly(), StateCapacity(10))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	for i := 0; i < 200; i++ {
+		require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, fmt.Sprintf("%d.txt", i)), []byte("DATA"), 0666))
+	}
+
+	var calls int
+T1:
+	for {
+		select {
+		case <-events:
+			calls++
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(200, calls)
+	require.Equal(10, watcher.Stats().State)
+}
+
+func TestStateStore(t *testing.T) {
+	require := require.New(t)
+
+	s := newStateStore(2)
+	s.set(stateKey{stateNewFile, "a"}, 1)
+	s.set(stateKey{stateNewFile, "b"}, 2)
+	_, ok := s.get(stateKey{stateNewFile, "a"})
+	require.True(ok)
+	s.set(stateKey{stateXattrs, "a"}, 3)
+
+	require.Equal(2, s.len())
+	_, ok = s.get(stateKey{stateNewFile, "b"})
+	require.False(ok)
+	v, ok := s.get(stateKey{stateNewFile, "a"})
+	require.True(ok)
+	require.Equal(1, v)
+
+	s.remove(stateKey{stateNewFile, "a"})
+	require.Equal(1, s.len())
+}
@@ -0,0 +1,57 @@
+package dirwatch
+
+import "sync/atomic"
+
+// Stats is a snapshot of the counters of a watcher.
+type Stats struct {
+	// Muted is the number of events dropped because their path was muted.
+	Muted uint64
+	// Throttled is the number of events dropped by PerDirRateLimit, per
+	// directory.
+	Throttled map[string]uint64
+	// State is the number of per-path entries kept, as bounded by
+	// StateCapacity.
+	State int
+	// Goroutines is the number of goroutines delivering events and walking
+	// recursive roots, as bounded by MaxGoroutines.
+	Goroutines int
+	// Watched is the number of watched paths.
+	Watched int
+	// Delivered is the number of events sent for delivery.
+	Delivered uint64
+	// Filtered is the number of events dropped by the filters, the muted
+	// and throttled ones and the ones dropped by ContentHashGate included.
+	Filtered uint64
+	// Errors is the number of errors reported on the Errors channel, the
+	// ones dropped because it was full included.
+	Errors uint64
+	// PendingAdds is the number of paths waiting to be added.
+	PendingAdds int
+	// Undelivered is the number of events sent for delivery whose notify
+	// callbacks have not returned yet.
+	Undelivered int
+}
+
+type counters struct {
+	muted     uint64
+	delivered uint64
+	filtered  uint64
+	errors    uint64
+	watched   int64
+}
+
+// Stats returns a snapshot of the counters of the watcher.
+func (dw *Watcher) Stats() Stats {
+	return Stats{
+		Muted:       atomic.LoadUint64(&dw.counters.muted),
+		Throttled:   dw.throttledCounts(),
+		State:       dw.state.len(),
+		Goroutines:  dw.budget.used(),
+		Watched:     int(atomic.LoadInt64(&dw.counters.watched)),
+		Delivered:   atomic.LoadUint64(&dw.counters.delivered),
+		Filtered:    atomic.LoadUint64(&dw.counters.filtered),
+		Errors:      atomic.LoadUint64(&dw.counters.errors),
+		PendingAdds: dw.adds.len(),
+		Undelivered: dw.undelivered.count(),
+	}
+}
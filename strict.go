@@ -0,0 +1,34 @@
+package dirwatch
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// StrictRoots makes adding a root that does not exist, or is not a
+// directory, an error, instead of silently watching nothing; e.g. for a
+// CLI, to report a mistyped directory. AddContext returns the error and
+// Add reports it on Errors. It only applies to the added roots; the
+// sub-directories found while walking a recursive root that disappear in
+// the meantime are still skipped silently.
+func StrictRoots() Option {
+	return func(opt *options) {
+		opt.strictRoots = true
+	}
+}
+
+// strictRoot returns the error StrictRoots reports for the root at path,
+// if any.
+func (dw *Watcher) strictRoot(path string) error {
+	inf, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return &kindError{kind: ErrRootNotFound, cause: errors.Wrapf(err, "root %s does not exist", path)}
+	case err != nil:
+		return nil
+	case !inf.IsDir():
+		return &kindError{kind: ErrNotDirectory, cause: errors.Errorf("root %s is not a directory", path)}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictRoots(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-strict")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fp := filepath.Join(rootDirectory, "file.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+
+	watcher := New(Notify(func(Event) {}), StrictRoots())
+	defer watcher.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	require.NoError(watcher.AddContext(ctx, rootDirectory, true))
+
+	err = watcher.AddContext(ctx, filepath.Join(rootDirectory, "typo"), true)
+	require.Error(err)
+	require.Contains(err.Error(), "does not exist")
+
+	err = watcher.AddContext(ctx, fp, false)
+	require.Error(err)
+	require.Contains(err.Error(), "is not a directory")
+
+	watcher.Add(filepath.Join(rootDirectory, "missing"), false)
+	select {
+	case err := <-watcher.Errors():
+		require.Contains(err.Error(), "does not exist")
+	case <-time.After(time.Second * 5):
+		require.FailNow("missing root not reported")
+	}
+
+	// without strict mode, a missing root is skipped silently
+	lenient := New(Notify(func(Event) {}))
+	defer lenient.Stop()
+	require.NoError(lenient.AddContext(ctx, filepath.Join(rootDirectory, "typo"), true))
+}
@@ -0,0 +1,69 @@
+package dirwatch
+
+import "sync"
+
+// subscribers maps the channels of the subscribers to the operations they
+// receive the events of; a zero Op stands for every event.
+type subscribers struct {
+	mx  sync.Mutex
+	set map[chan Event]Op
+}
+
+// Subscribe returns a channel, buffered by buffer, that receives every
+// event delivered to notify, and a function that ends the subscription and
+// closes the channel. A subscriber whose buffer is full misses events, so a
+// slow subscriber never holds up the watcher. Events arrive in the order
+// they were observed, unless ContentHashGate or SniffContentType is used.
+// The channel is closed when the watcher stops, too.
+func (dw *Watcher) Subscribe(buffer int) (<-chan Event, func()) {
+	events := make(chan Event, buffer)
+	dw.subscribe(events, 0)
+	return events, func() { dw.unsubscribe(events) }
+}
+
+// subscribe adds events to the subscribers, receiving the events with one
+// of the op bits, or every event for a zero op.
+func (dw *Watcher) subscribe(events chan Event, op Op) {
+	dw.subs.mx.Lock()
+	defer dw.subs.mx.Unlock()
+	select {
+	case <-dw.stopped():
+		close(events)
+		return
+	default:
+	}
+	dw.subs.set[events] = op
+}
+
+func (dw *Watcher) unsubscribe(events chan Event) {
+	dw.subs.mx.Lock()
+	defer dw.subs.mx.Unlock()
+	if _, ok := dw.subs.set[events]; ok {
+		delete(dw.subs.set, events)
+		close(events)
+	}
+}
+
+func (dw *Watcher) unsubscribeAll() {
+	dw.subs.mx.Lock()
+	defer dw.subs.mx.Unlock()
+	for events := range dw.subs.set {
+		delete(dw.subs.set, events)
+		close(events)
+	}
+}
+
+// publish sends ev to the subscribers with room for it.
+func (dw *Watcher) publish(ev Event) {
+	dw.subs.mx.Lock()
+	defer dw.subs.mx.Unlock()
+	for events, op := range dw.subs.set {
+		if op != 0 && ev.Op&op == 0 {
+			continue
+		}
+		select {
+		case events <- ev:
+		default:
+		}
+	}
+}
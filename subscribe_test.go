@@ -0,0 +1,63 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-subscribe")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	watcher := New(Notify(func(Event) {}))
+	defer watcher.Stop()
+
+	first, cancel := watcher.Subscribe(100)
+	second, _ := watcher.Subscribe(100)
+
+	require.NoError(watcher.AddContext(context.Background(), rootDirectory, false))
+	name := filepath.Join(rootDirectory, "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+
+	for _, events := range []<-chan Event{first, second} {
+		select {
+		case ev := <-events:
+			require.Equal(name, ev.Name)
+		case <-time.After(time.Second):
+			require.Fail("event not received")
+		}
+	}
+
+	cancel()
+	cancel()
+	for range first {
+	}
+
+	watcher.Stop()
+	select {
+	case <-func() chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			for range second {
+			}
+			close(done)
+		}()
+		return done
+	}():
+	case <-time.After(time.Second):
+		require.Fail("subscription not closed on stop")
+	}
+
+	late, _ := watcher.Subscribe(1)
+	_, ok := <-late
+	require.False(ok)
+}
@@ -0,0 +1,23 @@
+package dirwatch
+
+import "os"
+
+// ReportSymlinks sets Event.IsSymlink for the events of symbolic links, for
+// tools that manage links themselves, like symlink farms. It costs an extra
+// os.Lstat per event.
+func ReportSymlinks() Option {
+	return func(opt *options) {
+		opt.symlinks = true
+	}
+}
+
+// markSymlink sets the IsSymlink field of ev, as set by ReportSymlinks.
+func (dw *Watcher) markSymlink(ev Event) Event {
+	if !dw.symlinks {
+		return ev
+	}
+	if inf, err := os.Lstat(ev.Name); err == nil {
+		ev.IsSymlink = inf.Mode()&os.ModeSymlink != 0
+	}
+	return ev
+}
@@ -0,0 +1,52 @@
+//go:build !windows
+// +build !windows
+
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportSymlinks(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-symlink")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	target := filepath.Join(rootDirectory, "target.txt")
+	link := filepath.Join(rootDirectory, "link.txt")
+	require.NoError(ioutil.WriteFile(target, []byte("DATA"), 0644))
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		ReportSymlinks())
+	defer watcher.Stop()
+	watcher.Add(rootDirectory, false)
+	time.Sleep(time.Millisecond * 100)
+
+	next := func(name string) Event {
+		for {
+			select {
+			case ev := <-events:
+				if ev.Name == name {
+					return ev
+				}
+			case <-time.After(time.Second * 5):
+				require.FailNow("no event", name)
+			}
+		}
+	}
+
+	require.NoError(os.Symlink(target, link))
+	require.True(next(link).IsSymlink)
+
+	require.NoError(ioutil.WriteFile(target, []byte("MORE DATA"), 0644))
+	require.False(next(target).IsSymlink)
+}
@@ -0,0 +1,55 @@
+package dirwatch
+
+// IncludeTags delivers only the events of the roots tagged with one of
+// tags, using AddWithTag. A root tag is either a string, or a []string for
+// a root with several tags. An event gets the tags of the root it is
+// attributed to (see Event.Root), the longest one containing it.
+func IncludeTags(tags ...string) Option {
+	return func(opt *options) {
+		opt.includeTags = append(opt.includeTags, tags...)
+	}
+}
+
+// ExcludeTags drops the events of the roots tagged with one of tags, like
+// IncludeTags does for the other roots. It takes precedence over
+// IncludeTags.
+func ExcludeTags(tags ...string) Option {
+	return func(opt *options) {
+		opt.excludeTags = append(opt.excludeTags, tags...)
+	}
+}
+
+// tagsOf returns the tags of a root, as set by AddWithTag.
+func tagsOf(tag interface{}) []string {
+	switch v := tag.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// excludedByTag reports whether ev is dropped by IncludeTags or
+// ExcludeTags.
+func (dw *Watcher) excludedByTag(ev Event) bool {
+	if len(dw.includeTags) == 0 && len(dw.excludeTags) == 0 {
+		return false
+	}
+	tags := tagsOf(ev.Tag)
+	if anyTag(tags, dw.excludeTags) {
+		return true
+	}
+	return len(dw.includeTags) > 0 && !anyTag(tags, dw.includeTags)
+}
+
+func anyTag(tags, set []string) bool {
+	for _, t := range tags {
+		for _, s := range set {
+			if t == s {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagFilters(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-tags")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	payments := filepath.Join(rootDirectory, "payments")
+	search := filepath.Join(rootDirectory, "search")
+	legacy := filepath.Join(payments, "legacy")
+	for _, dir := range []string{payments, search, legacy} {
+		require.NoError(os.MkdirAll(dir, 0755))
+	}
+
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		IncludeTags("team-a", "team-b"),
+		ExcludeTags("frozen"))
+	defer watcher.Stop()
+	watcher.AddWithTag(payments, true, []string{"team-a", "prod"})
+	watcher.AddWithTag(search, true, "team-c")
+	// the longest root wins, so legacy is excluded though payments is not
+	watcher.AddWithTag(legacy, true, []string{"team-a", "frozen"})
+	time.Sleep(time.Millisecond * 100)
+
+	for _, dir := range []string{payments, search, legacy} {
+		require.NoError(ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("DATA"), 0644))
+	}
+
+	seen := make(map[string]bool)
+	for done := false; !done; {
+		select {
+		case ev := <-events:
+			seen[ev.Name] = true
+		case <-time.After(time.Millisecond * 300):
+			done = true
+		}
+	}
+	require.Equal(map[string]bool{filepath.Join(payments, "file.txt"): true}, seen)
+}
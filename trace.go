@@ -0,0 +1,49 @@
+package dirwatch
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// Trace turns on, or off, logging every decision the watcher makes about
+// path and the paths under it: adds, skips, exclusions, received events and
+// whether they were filtered or delivered. The lines are logged with the
+// logger, prefixed by TRACE. It is meant for debugging why the events of a
+// path do not arrive, without logging for the whole tree.
+func (dw *Watcher) Trace(path string, on bool) {
+	v, err := filepath.Abs(path)
+	if err != nil {
+		dw.fail(err)
+		return
+	}
+	dw.tracing.mx.Lock()
+	defer dw.tracing.mx.Unlock()
+	if on {
+		dw.tracing.paths[v] = struct{}{}
+	} else {
+		delete(dw.tracing.paths, v)
+	}
+	atomic.StoreInt32(&dw.tracing.count, int32(len(dw.tracing.paths)))
+}
+
+type tracing struct {
+	mx    sync.RWMutex
+	paths map[string]struct{}
+	count int32
+}
+
+// trace logs args about p if p is traced. It is cheap when nothing is.
+func (dw *Watcher) trace(p string, args ...interface{}) {
+	if atomic.LoadInt32(&dw.tracing.count) == 0 {
+		return
+	}
+	dw.tracing.mx.RLock()
+	defer dw.tracing.mx.RUnlock()
+	for traced := range dw.tracing.paths {
+		if isUnder(p, traced) {
+			dw.logger(append([]interface{}{"TRACE", p}, args...)...)
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-trace")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	traced := filepath.Join(rootDirectory, "traced")
+	other := filepath.Join(rootDirectory, "other")
+	require.NoError(os.Mkdir(traced, 0777))
+	require.NoError(os.Mkdir(other, 0777))
+
+	var (
+		mx    sync.Mutex
+		lines []string
+	)
+	logger := func(args ...interface{}) {
+		mx.Lock()
+		defer mx.Unlock()
+		lines = append(lines, fmt.Sprintln(args...))
+	}
+	watcher := New(Notify(func(Event) {}), Logger(logger), Exclude("**/*.tmp"))
+	defer watcher.Stop()
+	watcher.Trace(traced, true)
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	fp := filepath.Join(traced, "a.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(filepath.Join(traced, "b.tmp"), []byte("DATA"), 0644))
+	require.NoError(ioutil.WriteFile(filepath.Join(other, "c.txt"), []byte("DATA"), 0644))
+	<-time.After(time.Millisecond * 100)
+
+	mx.Lock()
+	all := strings.Join(lines, "")
+	mx.Unlock()
+	require.Contains(all, "TRACE "+traced+" watched, recursive: false")
+	require.Contains(all, "TRACE "+fp+" received CREATE")
+	require.Contains(all, "TRACE "+fp+" delivered CREATE")
+	require.Contains(all, "excluded by pattern **/*.tmp")
+	require.NotContains(all, other)
+
+	watcher.Trace(traced, false)
+	mx.Lock()
+	lines = nil
+	mx.Unlock()
+	require.NoError(ioutil.WriteFile(fp, []byte("MORE"), 0644))
+	<-time.After(time.Millisecond * 100)
+	mx.Lock()
+	defer mx.Unlock()
+	require.Empty(lines)
+}
@@ -0,0 +1,156 @@
+package dirwatch
+
+import (
+	"os"
+	"sort"
+	"time"
+)
+
+// VerifyWithStat confirms that the path of a Create or Write event exists
+// before delivering it, and sets Size and ModTime of the event from its
+// current metadata. On some file systems an event arrives before the change
+// is visible; then the path is stat'ed again, up to 3 times, 10ms apart,
+// and the event is dropped if the path never shows up. This adds up to 30ms
+// of latency to such events, during which the watcher does not process
+// other events. A Write whose path still has the size and modification
+// time seen for its previous event is held back and stat'ed again the same
+// way, in the background, then delivered with what was seen last; the
+// Writes of that path arriving meanwhile are folded into it.
+func VerifyWithStat() Option {
+	return func(opt *options) {
+		opt.verify = true
+	}
+}
+
+const (
+	verifyAttempts = 3
+	verifyDelay    = time.Millisecond * 10
+)
+
+// verifiedStat is the metadata of a path as last seen by VerifyWithStat.
+type verifiedStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// verifyStat stats name again while it seems missing, or stale after a
+// Write, as set by VerifyWithStat. It runs on the agent goroutine.
+func (dw *Watcher) verifyStat(ev Event, inf os.FileInfo, err error) (os.FileInfo, error) {
+	if !dw.verify {
+		return inf, err
+	}
+	key := stateKey{stateVerify, ev.Name}
+	if ev.Op&(Remove|Rename) != 0 {
+		dw.state.remove(key)
+		delete(dw.stale.pending, ev.Name)
+	}
+	if ev.Op&(Create|Write) == 0 {
+		return inf, err
+	}
+	for attempt := 0; os.IsNotExist(err) && attempt < verifyAttempts; attempt++ {
+		select {
+		case <-dw.clock.After(verifyDelay):
+		case <-dw.stopped():
+			return inf, err
+		}
+		inf, err = os.Stat(ev.Name)
+	}
+	if inf != nil {
+		dw.state.set(key, verifiedStat{size: inf.Size(), modTime: inf.ModTime()})
+	}
+	return inf, err
+}
+
+// staleWrites holds the Writes of VerifyWithStat whose paths are being
+// stat'ed again, by path. It is owned by the agent goroutine.
+type staleWrites struct {
+	pending map[string]Event
+	// passing is the path whose held Write is being processed again
+	passing string
+}
+
+// holdStale reports whether ev is a Write held back by VerifyWithStat
+// because inf, the current info of its path, is still the one seen for its
+// previous event. It runs on the agent goroutine.
+func (dw *Watcher) holdStale(ev Event, inf os.FileInfo) bool {
+	if !dw.verify || ev.Op&Write == 0 || inf == nil || ev.Name == dw.stale.passing {
+		return false
+	}
+	if _, ok := dw.stale.pending[ev.Name]; ok {
+		dw.stale.pending[ev.Name] = ev
+		return true
+	}
+	if !dw.staleStat(ev.Name, inf) {
+		return false
+	}
+	dw.trace(ev.Name, "held: stale stat")
+	dw.stale.pending[ev.Name] = ev
+	dw.restat(ev.Name, 1)
+	return true
+}
+
+// staleStat reports whether inf is the info last seen for name.
+func (dw *Watcher) staleStat(name string, inf os.FileInfo) bool {
+	v, ok := dw.state.get(stateKey{stateVerify, name})
+	if !ok {
+		return false
+	}
+	last := v.(verifiedStat)
+	return inf.Size() == last.size && inf.ModTime().Equal(last.modTime)
+}
+
+// restat stats name again after verifyDelay, off the agent goroutine, for
+// the attempt-th time.
+func (dw *Watcher) restat(name string, attempt int) {
+	dw.clock.AfterFunc(verifyDelay, func() {
+		inf, err := os.Stat(name)
+		dw.call(func(backend) {
+			ev, ok := dw.stale.pending[name]
+			if !ok {
+				return
+			}
+			if err == nil && dw.staleStat(name, inf) && attempt < verifyAttempts {
+				dw.restat(name, attempt+1)
+				return
+			}
+			delete(dw.stale.pending, name)
+			dw.passStale(ev)
+		})
+	})
+}
+
+// passStale processes ev, a Write held by VerifyWithStat, again.
+func (dw *Watcher) passStale(ev Event) {
+	dw.stale.passing = ev.Name
+	dw.onEvent(ev)
+	dw.stale.passing = ""
+}
+
+// releaseStale processes the held Writes of VerifyWithStat right away, for
+// StopGraceful. It runs on the agent goroutine.
+func (dw *Watcher) releaseStale() {
+	names := make([]string, 0, len(dw.stale.pending))
+	for name := range dw.stale.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ev := dw.stale.pending[name]
+		delete(dw.stale.pending, name)
+		dw.passStale(ev)
+	}
+}
+
+// unverified reports whether ev is dropped by VerifyWithStat, and sets
+// its metadata otherwise.
+func (dw *Watcher) unverified(ev *Event, inf os.FileInfo) bool {
+	if !dw.verify || ev.Op&(Create|Write) == 0 {
+		return false
+	}
+	if inf == nil {
+		return true
+	}
+	ev.Size = inf.Size()
+	ev.ModTime = inf.ModTime()
+	return false
+}
@@ -0,0 +1,154 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWithStat(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-verify")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		VerifyWithStat(),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+
+	// the event arrives before the file is visible
+	fp := filepath.Join(rootDirectory, "late.txt")
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Create}
+	clock.waitTimers(1)
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+	clock.Advance(verifyDelay)
+
+	select {
+	case ev := <-events:
+		require.Equal(fp, ev.Name)
+		require.Equal(int64(4), ev.Size)
+		inf, err := os.Stat(fp)
+		require.NoError(err)
+		require.True(inf.ModTime().Equal(ev.ModTime))
+	case <-time.After(time.Second * 5):
+		require.FailNow("verified event not delivered")
+	}
+
+	// a write that does not show yet is stat'ed again
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	clock.waitTimers(2)
+	require.NoError(ioutil.WriteFile(fp, []byte("MORE DATA"), 0644))
+	clock.Advance(verifyDelay)
+	select {
+	case ev := <-events:
+		require.Equal(fp, ev.Name)
+		require.Equal(int64(9), ev.Size)
+	case <-time.After(time.Second * 5):
+		require.FailNow("verified write not delivered")
+	}
+
+	// a file that never shows up is not reported
+	missing := filepath.Join(rootDirectory, "missing.txt")
+	fake.events <- fsnotify.Event{Name: missing, Op: fsnotify.Write}
+	for i := 0; i < verifyAttempts; i++ {
+		clock.waitTimers(3 + i)
+		clock.Advance(verifyDelay)
+	}
+	select {
+	case ev := <-events:
+		require.FailNow("unverified event delivered", ev.Name)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestVerifyWithStatBurst(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-verify")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fp := filepath.Join(rootDirectory, "download.bin")
+	require.NoError(ioutil.WriteFile(fp, []byte("CHUNK"), 0644))
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var events = make(chan Event, 200)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		VerifyWithStat(),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	select {
+	case <-events:
+	case <-time.After(time.Second * 5):
+		require.FailNow("first write not delivered")
+	}
+
+	// the queued writes of a download all see its final stat
+	for i := 0; i < 100; i++ {
+		fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	}
+	start := time.Now()
+	require.NoError(watcher.call(func(backend) {}))
+	require.True(time.Since(start) < time.Millisecond*500, "agent held up for %v", time.Since(start))
+
+	for i := 0; i < verifyAttempts; i++ {
+		clock.waitTimers(1 + i)
+		clock.Advance(verifyDelay)
+	}
+	select {
+	case ev := <-events:
+		require.Equal(fp, ev.Name)
+		require.Equal(Write, ev.Op)
+	case <-time.After(time.Second * 5):
+		require.FailNow("held write not delivered")
+	}
+	select {
+	case ev := <-events:
+		require.FailNow("held writes delivered more than once", ev.Name)
+	case <-time.After(time.Millisecond * 200):
+	}
+}
+
+func TestVerifyWithStatStopGraceful(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-verify")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	fp := filepath.Join(rootDirectory, "text.txt")
+	require.NoError(ioutil.WriteFile(fp, []byte("DATA"), 0644))
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		VerifyWithStat(),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	fake.events <- fsnotify.Event{Name: fp, Op: fsnotify.Write}
+	clock.waitTimers(1)
+
+	// the held write is delivered without waiting for its stats
+	require.NoError(watcher.StopGraceful(time.Second * 5))
+	require.Len(events, 2)
+}
@@ -0,0 +1,149 @@
+package dirwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+type fileWaiter struct {
+	target string
+	poke   chan struct{}
+}
+
+// WaitForFile blocks until a file or directory exists at path, returning
+// nil, or until ctx is done, returning ctx.Err(). It returns right away if
+// path already exists. It watches the nearest existing ancestor of path, so
+// the parent directory does not need to exist either. The directories it
+// watches for this are not roots: their events are not delivered.
+func (dw *Watcher) WaitForFile(ctx context.Context, path string) error {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	w := &fileWaiter{target: target, poke: make(chan struct{}, 1)}
+	var dir string
+	defer func() {
+		if dir != "" {
+			dw.call(func(watcher backend) { dw.unwait(watcher, w, dir) })
+		}
+	}()
+
+	for {
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		next := existingAncestor(target)
+		if next != dir {
+			var addErr error
+			err := dw.call(func(watcher backend) {
+				if dir != "" {
+					dw.unwait(watcher, w, dir)
+				}
+				addErr = dw.wait(watcher, w, next)
+			})
+			if err != nil {
+				return err
+			}
+			if addErr != nil {
+				return addErr
+			}
+			dir = next
+			// it may have appeared before the watch was in place
+			continue
+		}
+		select {
+		case <-w.poke:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-dw.stopped():
+			return ErrWatcherStopped
+		}
+	}
+}
+
+// wait registers w, watching dir for it. It runs on the agent goroutine.
+func (dw *Watcher) wait(watcher backend, w *fileWaiter, dir string) error {
+	if err := dw.watchDir(watcher, dir); err != nil {
+		return err
+	}
+	dw.waiters[w] = struct{}{}
+	return nil
+}
+
+// unwait removes w, and stops watching dir if nothing else needs it. It
+// runs on the agent goroutine.
+func (dw *Watcher) unwait(watcher backend, w *fileWaiter, dir string) {
+	delete(dw.waiters, w)
+	dw.unwatchDir(watcher, dir)
+}
+
+// watchDir watches dir on behalf of something other than a root, counting
+// its users. Its events are not delivered, unless it is watched as part of
+// a root too. It runs on the agent goroutine.
+func (dw *Watcher) watchDir(watcher backend, dir string) error {
+	if dw.waitDirs[dir] == 0 {
+		if _, ok := dw.paths[dir]; !ok {
+			if err := watcher.Add(dir); err != nil {
+				return err
+			}
+		}
+	}
+	dw.waitDirs[dir]++
+	return nil
+}
+
+// unwatchDir releases a watchDir of dir, and stops watching it once it has
+// no users left. It runs on the agent goroutine.
+func (dw *Watcher) unwatchDir(watcher backend, dir string) {
+	dw.waitDirs[dir]--
+	if dw.waitDirs[dir] > 0 {
+		return
+	}
+	delete(dw.waitDirs, dir)
+	if _, ok := dw.paths[dir]; !ok {
+		watcher.Remove(dir)
+	}
+}
+
+// wakeWaiters pokes the waiters of name, or of a path under it, and
+// reports whether the event of name only concerns the waiters, or the
+// files bound by BindMountSafe, and so is not to be delivered. It runs on
+// the agent goroutine.
+func (dw *Watcher) wakeWaiters(name string) (waitOnly bool) {
+	if len(dw.waitDirs) == 0 {
+		return false
+	}
+	for w := range dw.waiters {
+		if isUnder(w.target, name) {
+			select {
+			case w.poke <- struct{}{}:
+			default:
+			}
+		}
+	}
+	dir := filepath.Dir(name)
+	if _, ok := dw.paths[name]; ok {
+		return false
+	}
+	if _, ok := dw.paths[dir]; ok {
+		return false
+	}
+	return dw.waitDirs[name] > 0 || dw.waitDirs[dir] > 0
+}
+
+// existingAncestor returns the nearest ancestor directory of path that
+// exists.
+func existingAncestor(path string) string {
+	dir := filepath.Dir(path)
+	for {
+		if inf, err := os.Stat(dir); err == nil && inf.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,63 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForFile(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-wait")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }))
+	defer watcher.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// already exists
+	existing := filepath.Join(rootDirectory, "existing")
+	require.NoError(ioutil.WriteFile(existing, nil, 0666))
+	require.NoError(watcher.WaitForFile(ctx, existing))
+
+	// appears later, under directories that do not exist yet
+	marker := filepath.Join(rootDirectory, "a", "b", "done")
+	go func() {
+		<-time.After(time.Millisecond * 100)
+		os.Mkdir(filepath.Join(rootDirectory, "a"), 0777)
+		<-time.After(time.Millisecond * 50)
+		os.Mkdir(filepath.Join(rootDirectory, "a", "b"), 0777)
+		<-time.After(time.Millisecond * 50)
+		ioutil.WriteFile(marker, nil, 0666)
+	}()
+	require.NoError(watcher.WaitForFile(ctx, marker))
+
+	// times out
+	short, cancelShort := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancelShort()
+	err = watcher.WaitForFile(short, filepath.Join(rootDirectory, "never"))
+	require.Equal(context.DeadlineExceeded, err)
+
+	// the directories watched for waiting are not roots
+	select {
+	case ev := <-events:
+		require.Failf("event delivered", "%v", ev)
+	case <-time.After(time.Millisecond * 100):
+	}
+	var waiters, waitDirs int
+	require.NoError(watcher.call(func(backend) {
+		waiters, waitDirs = len(watcher.waiters), len(watcher.waitDirs)
+	}))
+	require.Zero(waiters)
+	require.Zero(waitDirs)
+}
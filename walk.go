@@ -0,0 +1,278 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileInfo is the metadata of a path, as recorded by Snapshot.
+type FileInfo struct {
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mod_time"`
+	Mode    os.FileMode `json:"mode"`
+	IsDir   bool        `json:"is_dir"`
+}
+
+// Snapshot walks the tree under root the way a recursive add does, and
+// returns the metadata of every path found, root included: excluded paths
+// are skipped, and so are the directories rejected by WatchFilter or
+// skipped by RecurseOnlyRecent, along with their sub-trees, and the
+// sub-trees of the directories of TreatAsLeaf. Errors on paths below root
+// are reported and the walk goes on; an error on root itself is returned.
+func (dw *Watcher) Snapshot(root string) (map[string]FileInfo, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	inf, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]FileInfo{root: snapshotInfo(inf)}
+	if !inf.IsDir() {
+		return res, nil
+	}
+	// the directories come from the walk, their files from their listings
+	files := func(dir string) error {
+		list, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, f := range list {
+			path := filepath.Join(dir, f.Name())
+			if f.IsDir() || dw.excludePath(path) {
+				continue
+			}
+			res[path] = snapshotInfo(f)
+		}
+		return nil
+	}
+	if err := files(root); err != nil {
+		return nil, err
+	}
+	for dirs := range dw.dirTree(dw.ctx, root, walkWorkers, dw.fail) {
+		for _, dir := range dirs {
+			inf, err := os.Stat(dir)
+			if err == nil {
+				res[dir] = snapshotInfo(inf)
+				err = files(dir)
+			}
+			if err != nil {
+				dw.fail(errors.WithStack(err))
+			}
+		}
+	}
+	if dw.ctx.Err() != nil {
+		return nil, ErrWatcherStopped
+	}
+	return res, nil
+}
+
+func snapshotInfo(f os.FileInfo) FileInfo {
+	return FileInfo{
+		Size:    f.Size(),
+		ModTime: f.ModTime(),
+		Mode:    f.Mode(),
+		IsDir:   f.IsDir(),
+	}
+}
+
+// walkWorkers is the number of directories AddRecursiveSync reads at once.
+const walkWorkers = 16
+
+// walkBatch is the number of directories dirTree lists in a batch.
+const walkBatch = 64
+
+// dirListing is the result of reading the sub-directories of a directory,
+// available once done is closed.
+type dirListing struct {
+	dir  string
+	dirs []string
+	err  error
+	done chan struct{}
+}
+
+// dirTree lists the sub-directories of queryRoot in batches, in the order
+// of filepath.Walk: in lexical order, each directory right before the ones
+// under it, so that the recursive adds register the directories in that
+// order, as reported by WatchSetChanges. It reads up to workers directories
+// at once, ahead of the ones being listed, and passes the errors of reading
+// them to onErr. queryRoot itself is never listed: it is registered by the
+// add that started the walk, so listing it would register it twice. The
+// walk stops early once ctx is done.
+// Excluded directories, the ones rejected by WatchFilter and the ones
+// skipped by RecurseOnlyRecent are skipped, along with their sub-trees;
+// the directories of TreatAsLeaf are listed, but not their sub-trees.
+func (dw *Watcher) dirTree(ctx context.Context, queryRoot string, workers int, onErr func(error)) <-chan []string {
+	found := make(chan []string)
+	go func() {
+		defer close(found)
+
+		read := func(l *dirListing) {
+			l.dirs, l.err = dw.subDirs(l.dir)
+			close(l.done)
+		}
+		var (
+			mx      sync.Mutex
+			cond    = sync.NewCond(&mx)
+			pending []*dirListing
+			closed  bool
+			wg      sync.WaitGroup
+		)
+		if workers > 1 {
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						mx.Lock()
+						for len(pending) == 0 && !closed {
+							cond.Wait()
+						}
+						if closed {
+							mx.Unlock()
+							return
+						}
+						l := pending[len(pending)-1]
+						pending = pending[:len(pending)-1]
+						mx.Unlock()
+						read(l)
+					}
+				}()
+			}
+			defer func() {
+				mx.Lock()
+				closed = true
+				mx.Unlock()
+				cond.Broadcast()
+				wg.Wait()
+			}()
+		}
+		// fetch reads dirs, in the background with workers; the first one
+		// is read first, as it is listed first
+		fetch := func(dirs []string) []*dirListing {
+			res := make([]*dirListing, len(dirs))
+			for i, dir := range dirs {
+				res[i] = &dirListing{dir: dir, done: make(chan struct{})}
+			}
+			if workers <= 1 {
+				for _, l := range res {
+					read(l)
+				}
+				return res
+			}
+			mx.Lock()
+			for i := len(res) - 1; i >= 0; i-- {
+				pending = append(pending, res[i])
+			}
+			mx.Unlock()
+			cond.Broadcast()
+			return res
+		}
+
+		var batch []string
+		flush := func() bool {
+			if len(batch) == 0 {
+				return true
+			}
+			select {
+			case found <- batch:
+				batch = nil
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		var visit func(l *dirListing) bool
+		visit = func(l *dirListing) bool {
+			select {
+			case <-l.done:
+			case <-ctx.Done():
+				return false
+			}
+			if l.err != nil {
+				onErr(l.err)
+			}
+			// a leaf directory is watched, but not walked into
+			var into []string
+			for _, sub := range l.dirs {
+				if !dw.isLeaf(sub) {
+					into = append(into, sub)
+				}
+			}
+			subs := fetch(into)
+			for _, sub := range l.dirs {
+				batch = append(batch, sub)
+				if len(batch) == walkBatch && !flush() {
+					return false
+				}
+				if len(subs) > 0 && subs[0].dir == sub {
+					if !visit(subs[0]) {
+						return false
+					}
+					subs = subs[1:]
+				}
+			}
+			return true
+		}
+		if visit(fetch([]string{queryRoot})[0]) {
+			flush()
+		}
+	}()
+	return found
+}
+
+// subDirs lists the sub-directories of dir that are not excluded, in
+// lexical order.
+func (dw *Watcher) subDirs(dir string) ([]string, error) {
+	list, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.WithStack(tooLong(classify(err), dir))
+	}
+	var res []string
+	for _, f := range list {
+		if !f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		if dw.excludePath(path) {
+			continue
+		}
+		if dw.watchFilter != nil && !dw.watchFilter(path, f) {
+			continue
+		}
+		if dw.cold(f) {
+			continue
+		}
+		res = append(res, path)
+	}
+	return res, nil
+}
+
+// walk calls fn for root and every path under it, in lexical order. Excluded
+// paths are skipped, along with their sub-trees. Errors on paths below root
+// are reported and the walk goes on; an error on root itself is returned.
+func (dw *Watcher) walk(root string, fn func(path string, f os.FileInfo) error) error {
+	return filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			dw.fail(errors.WithStack(err))
+			return nil
+		}
+		if path != root && dw.excludePath(path) {
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(path, f)
+	})
+}
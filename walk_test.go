@@ -0,0 +1,238 @@
+package dirwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-snapshot")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "lab1", "lab2"), 0777))
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "node_modules", "pkg"), 0777))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "lab1", "lab2", "text.txt"), []byte("DATA"), 0777))
+
+	watcher := New(Notify(func(Event) {}), Exclude(filepath.Join(rootDirectory, "node_modules")))
+	defer watcher.Stop()
+
+	snap, err := watcher.Snapshot(rootDirectory)
+	require.NoError(err)
+	require.Len(snap, 4)
+	require.True(snap[rootDirectory].IsDir)
+	require.True(snap[filepath.Join(rootDirectory, "lab1", "lab2")].IsDir)
+	inf := snap[filepath.Join(rootDirectory, "lab1", "lab2", "text.txt")]
+	require.False(inf.IsDir)
+	require.Equal(int64(4), inf.Size)
+	require.NotContains(snap, filepath.Join(rootDirectory, "node_modules"))
+	require.NotContains(snap, filepath.Join(rootDirectory, "node_modules", "pkg"))
+
+	js, err := json.Marshal(snap)
+	require.NoError(err)
+	var decoded map[string]FileInfo
+	require.NoError(json.Unmarshal(js, &decoded))
+	require.Equal(inf.Size, decoded[filepath.Join(rootDirectory, "lab1", "lab2", "text.txt")].Size)
+	require.True(inf.ModTime.Equal(decoded[filepath.Join(rootDirectory, "lab1", "lab2", "text.txt")].ModTime))
+
+	_, err = watcher.Snapshot(filepath.Join(rootDirectory, "missing"))
+	require.Error(err)
+
+	// it skips what a recursive add skips
+	app := filepath.Join(rootDirectory, "Editor.app")
+	require.NoError(os.MkdirAll(filepath.Join(app, "Contents"), 0777))
+	require.NoError(ioutil.WriteFile(filepath.Join(app, "PkgInfo"), []byte("DATA"), 0777))
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "cache", "blobs"), 0777))
+	filtered := New(
+		Notify(func(Event) {}),
+		TreatAsLeaf("*.app"),
+		WatchFilter(func(path string, _ os.FileInfo) bool { return filepath.Base(path) != "cache" }))
+	defer filtered.Stop()
+	snap, err = filtered.Snapshot(rootDirectory)
+	require.NoError(err)
+	require.Contains(snap, app)
+	require.Contains(snap, filepath.Join(app, "PkgInfo"))
+	require.NotContains(snap, filepath.Join(app, "Contents"))
+	require.NotContains(snap, filepath.Join(rootDirectory, "cache"))
+	require.NotContains(snap, filepath.Join(rootDirectory, "cache", "blobs"))
+}
+
+func TestDirTree(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-dirtree")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	makeTree(t, rootDirectory, 3, 4)
+	require.NoError(os.MkdirAll(filepath.Join(rootDirectory, "node_modules", "pkg"), 0777))
+	require.NoError(ioutil.WriteFile(filepath.Join(rootDirectory, "d0", "text.txt"), []byte("DATA"), 0777))
+
+	watcher := New(Notify(func(Event) {}), Exclude(filepath.Join(rootDirectory, "node_modules")))
+	defer watcher.Stop()
+
+	var expected []string
+	require.NoError(filepath.Walk(rootDirectory, func(path string, f os.FileInfo, err error) error {
+		require.NoError(err)
+		if f.Name() == "node_modules" {
+			return filepath.SkipDir
+		}
+		if f.IsDir() && path != rootDirectory {
+			expected = append(expected, path)
+		}
+		return nil
+	}))
+	require.Len(expected, 3+9+27+81)
+
+	// read in parallel or not, the directories come in walk order
+	for _, workers := range []int{1, walkWorkers} {
+		var found []string
+		for dirs := range watcher.dirTree(context.Background(), rootDirectory, workers, watcher.fail) {
+			found = append(found, dirs...)
+		}
+		require.Equal(expected, found, "workers: %d", workers)
+	}
+}
+
+func TestRootAddedOnce(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-rootonce")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 2, 3)
+
+	fake := newFakeBackend()
+	watcher := New(
+		Notify(func(Event) {}),
+		withBackend(func() (backend, error) { return fake, nil }))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	<-time.After(time.Millisecond * 100)
+
+	require.NoError(filepath.Walk(rootDirectory, func(path string, f os.FileInfo, err error) error {
+		require.NoError(err)
+		if f.IsDir() {
+			require.Equal(1, fake.addCount(path), path)
+		}
+		return nil
+	}))
+}
+
+func BenchmarkDirTree(b *testing.B) {
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-dirtree-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(rootDirectory)
+
+	// 50 * 10 * 100 leaf directories
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 10; j++ {
+			for k := 0; k < 100; k++ {
+				dir := filepath.Join(rootDirectory, strconv.Itoa(i), strconv.Itoa(j), strconv.Itoa(k))
+				if err := os.MkdirAll(dir, 0777); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	watcher := New(Notify(func(Event) {}))
+	defer watcher.Stop()
+
+	for _, workers := range []int{1, walkWorkers} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for range watcher.dirTree(context.Background(), rootDirectory, workers, watcher.fail) {
+				}
+			}
+		})
+	}
+}
+
+// makeTree creates a tree of directories named d0, d1, ... under root,
+// with width directories at each of depth levels.
+func makeTree(t *testing.T, root string, width, depth int) {
+	if depth == 0 {
+		return
+	}
+	for i := 0; i < width; i++ {
+		dir := filepath.Join(root, "d"+strconv.Itoa(i))
+		require.NoError(t, os.Mkdir(dir, 0777))
+		makeTree(t, dir, width, depth-1)
+	}
+}
+
+func TestDirTreeStopped(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-dirtree")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 4, 4)
+
+	slow := func(string, os.FileInfo) bool {
+		<-time.After(time.Millisecond * 5)
+		return true
+	}
+	watcher := New(Notify(func(Event) {}), WatchFilter(slow))
+
+	tree := watcher.dirTree(watcher.ctx, rootDirectory, 1, watcher.fail)
+	found := len(<-tree)
+	watcher.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for dirs := range tree {
+			found += len(dirs)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail("walk did not stop")
+	}
+	require.True(found < 4+16+64+256, found)
+}
+
+func TestWalkTimeout(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-walk-timeout")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 4, 4)
+
+	slow := func(string, os.FileInfo) bool {
+		<-time.After(time.Millisecond * 5)
+		return true
+	}
+	watcher := New(
+		Notify(func(Event) {}),
+		WatchFilter(slow),
+		WalkTimeout(time.Millisecond*50),
+		Logger(func(...interface{}) {}))
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, true)
+	select {
+	case err := <-watcher.Errors():
+		require.Contains(err.Error(), "timed out")
+	case <-time.After(time.Second * 2):
+		require.Fail("no timeout reported")
+	}
+}
@@ -0,0 +1,213 @@
+package dirwatch
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrWatchLimit is reported on the Errors channel when PollOnWatchLimit
+// falls back to polling, because the backend ran out of watches, like
+// inotify does at fs.inotify.max_user_watches. It is of the kind
+// ErrWatchLimitReached.
+var ErrWatchLimit error = &kindError{
+	kind:  ErrWatchLimitReached,
+	cause: errors.New("watch limit reached, polling instead"),
+}
+
+// watchLimitRun is the number of consecutive adds that must fail for lack
+// of watches before PollOnWatchLimit falls back to polling.
+const watchLimitRun = 3
+
+// PollOnWatchLimit polls every interval the directories the watcher can not
+// watch because the backend ran out of watches (ENOSPC), instead of leaving
+// them unwatched. It kicks in once 3 adds in a row failed that way, for
+// those directories and every one that fails the same way afterwards, like
+// the rest of a recursive tree being walked. Failures before that are
+// reported as usual. The transition is reported once, as ErrWatchLimit on
+// the Errors channel. Polling compares listings, so it reports Create,
+// Write and Remove events of the entries of a directory, but not changes
+// that cancel out between two polls.
+func PollOnWatchLimit(interval time.Duration) Option {
+	return func(opt *options) {
+		opt.pollInterval = interval
+	}
+}
+
+type polledEntry struct {
+	size    int64
+	modTime time.Time
+}
+
+// polledDirs holds the directories polled by PollOnWatchLimit, with their
+// last listings.
+type polledDirs struct {
+	mx       sync.Mutex
+	listings map[string]map[string]polledEntry
+
+	// owned by the agent goroutine
+	limited bool
+	run     []string
+}
+
+// pollOverLimit reports whether path, which the backend failed to add with
+// err, gets polled instead; a nil err, for a successful add, breaks a run
+// of failures. It runs on the agent goroutine.
+func (dw *Watcher) pollOverLimit(path string, err error) bool {
+	if dw.pollInterval <= 0 {
+		return false
+	}
+	if err == nil || !errors.Is(err, syscall.ENOSPC) {
+		dw.polls.run = nil
+		return false
+	}
+	if dw.polls.limited {
+		dw.poll(path)
+		return true
+	}
+	dw.polls.run = append(dw.polls.run, path)
+	if len(dw.polls.run) < watchLimitRun {
+		return false
+	}
+	dw.polls.limited = true
+	dw.fail(ErrWatchLimit)
+	for _, p := range dw.polls.run[:len(dw.polls.run)-1] {
+		// these failed before the fallback, so they are not known yet
+		wp := dw.paths[p]
+		wp.dir, _ = isDir(p)
+		dw.registerPath(p, wp)
+		dw.poll(p)
+	}
+	dw.polls.run = nil
+	dw.poll(path)
+	return true
+}
+
+// poll starts polling dir, taking its current listing as a baseline.
+func (dw *Watcher) poll(dir string) {
+	listing, err := listPolled(dir)
+	if err != nil {
+		return
+	}
+	dw.trace(dir, "polled")
+	dw.polls.mx.Lock()
+	dw.polls.listings[dir] = listing
+	dw.polls.mx.Unlock()
+}
+
+// unpoll stops polling dir, if it is polled.
+func (dw *Watcher) unpoll(dir string) {
+	dw.polls.mx.Lock()
+	delete(dw.polls.listings, dir)
+	dw.polls.mx.Unlock()
+}
+
+// polled reports whether dir is polled instead of watched.
+func (dw *Watcher) polled(dir string) bool {
+	dw.polls.mx.Lock()
+	defer dw.polls.mx.Unlock()
+	_, ok := dw.polls.listings[dir]
+	return ok
+}
+
+func (dw *Watcher) polledCount() int {
+	dw.polls.mx.Lock()
+	defer dw.polls.mx.Unlock()
+	return len(dw.polls.listings)
+}
+
+// pollDirs polls the directories of PollOnWatchLimit every interval and
+// passes the changes to the agent, until the watcher stops.
+func (dw *Watcher) pollDirs() {
+	t := dw.clock.NewTimer(dw.pollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-dw.stopped():
+			return
+		case <-t.C():
+		}
+		if events := dw.pollChanges(); len(events) > 0 {
+			dw.call(func(backend) {
+				for _, ev := range events {
+					// its directory may have been removed since the poll
+					if _, ok := dw.paths[filepath.Dir(ev.Name)]; !ok {
+						continue
+					}
+					dw.onEvent(ev)
+				}
+			})
+		}
+		t.Reset(dw.pollInterval)
+	}
+}
+
+// pollChanges lists the polled directories and returns the events of
+// what changed since the last poll.
+func (dw *Watcher) pollChanges() []Event {
+	dw.polls.mx.Lock()
+	dirs := make([]string, 0, len(dw.polls.listings))
+	for dir := range dw.polls.listings {
+		dirs = append(dirs, dir)
+	}
+	dw.polls.mx.Unlock()
+	sort.Strings(dirs)
+
+	var events []Event
+	for _, dir := range dirs {
+		listing, err := listPolled(dir)
+		dw.polls.mx.Lock()
+		last, ok := dw.polls.listings[dir]
+		switch {
+		case !ok:
+		case err != nil:
+			// its own removal is reported by its parent
+			delete(dw.polls.listings, dir)
+		default:
+			dw.polls.listings[dir] = listing
+		}
+		dw.polls.mx.Unlock()
+		if !ok || err != nil {
+			continue
+		}
+		events = append(events, diffPolled(dir, last, listing, dw.clock.Now())...)
+	}
+	return events
+}
+
+func listPolled(dir string) (map[string]polledEntry, error) {
+	list, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	listing := make(map[string]polledEntry, len(list))
+	for _, inf := range list {
+		listing[inf.Name()] = polledEntry{size: inf.Size(), modTime: inf.ModTime()}
+	}
+	return listing, nil
+}
+
+func diffPolled(dir string, last, current map[string]polledEntry, now time.Time) []Event {
+	var events []Event
+	for name, entry := range current {
+		op := Write
+		if prev, ok := last[name]; !ok {
+			op = Create
+		} else if prev.size == entry.size && prev.modTime.Equal(entry.modTime) {
+			continue
+		}
+		events = append(events, Event{Name: filepath.Join(dir, name), Op: op, Time: now})
+	}
+	for name := range last {
+		if _, ok := current[name]; !ok {
+			events = append(events, Event{Name: filepath.Join(dir, name), Op: Remove, Time: now})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	return events
+}
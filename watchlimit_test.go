@@ -0,0 +1,166 @@
+package dirwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollOnWatchLimit(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-watchlimit")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	var dirs []string
+	for i := 0; i < 6; i++ {
+		dir := filepath.Join(rootDirectory, fmt.Sprintf("sub%d", i))
+		require.NoError(os.Mkdir(dir, 0755))
+		dirs = append(dirs, dir)
+	}
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	var (
+		mx    sync.Mutex
+		added int
+	)
+	// the root and one sub-directory fit, the rest runs out of watches
+	fake.addErr = func(string) error {
+		mx.Lock()
+		defer mx.Unlock()
+		if added == 2 {
+			return syscall.ENOSPC
+		}
+		added++
+		return nil
+	}
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		PollOnWatchLimit(time.Second),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+	clock.waitTimers(1)
+	watcher.Add(rootDirectory, true)
+
+	var failures int
+	for limited := false; !limited; {
+		select {
+		case err := <-watcher.Errors():
+			require.True(errors.Is(err, ErrWatchLimitReached))
+			if err == ErrWatchLimit {
+				limited = true
+			} else {
+				require.True(errors.Is(err, syscall.ENOSPC))
+				failures++
+			}
+		case <-time.After(time.Second * 5):
+			require.FailNow("watch limit was not reported")
+		}
+	}
+	require.Equal(watchLimitRun-1, failures)
+	for watcher.polledCount() < len(dirs)-1 {
+		time.Sleep(time.Millisecond * 10)
+	}
+	require.Equal(len(dirs)-1, watcher.polledCount())
+
+	var polled []string
+	for _, dir := range dirs {
+		if !fake.watched(dir) {
+			require.NoError(ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("DATA"), 0644))
+			polled = append(polled, filepath.Join(dir, "file.txt"))
+		}
+	}
+	clock.Advance(time.Second)
+	var created []string
+	for len(created) < len(polled) {
+		select {
+		case ev := <-events:
+			require.Equal(Create, ev.Op)
+			created = append(created, ev.Name)
+		case <-time.After(time.Second * 5):
+			require.FailNow("polled changes were not reported")
+		}
+	}
+	require.ElementsMatch(polled, created)
+
+	require.NoError(os.Remove(polled[0]))
+	clock.waitTimers(2)
+	clock.Advance(time.Second)
+	select {
+	case ev := <-events:
+		require.Equal(polled[0], ev.Name)
+		require.Equal(Remove, ev.Op)
+	case <-time.After(time.Second * 5):
+		require.FailNow("polled removal was not reported")
+	}
+}
+
+func TestPollOnWatchLimitRemove(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-watchlimit")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	var dirs []string
+	for i := 0; i < 4; i++ {
+		dir := filepath.Join(rootDirectory, fmt.Sprintf("sub%d", i))
+		require.NoError(os.Mkdir(dir, 0755))
+		dirs = append(dirs, dir)
+	}
+
+	clock := newFakeClock()
+	fake := newFakeBackend()
+	// only the root fits
+	fake.addErr = func(path string) error {
+		if path == rootDirectory {
+			return nil
+		}
+		return syscall.ENOSPC
+	}
+	var events = make(chan Event, 100)
+	watcher := New(
+		Notify(func(ev Event) { events <- ev }),
+		PollOnWatchLimit(time.Second),
+		withBackend(func() (backend, error) { return fake, nil }),
+		withClock(clock))
+	defer watcher.Stop()
+	clock.waitTimers(1)
+	watcher.Add(rootDirectory, true)
+
+	deadline := time.After(time.Second * 5)
+	for watcher.polledCount() < len(dirs) {
+		select {
+		case <-watcher.Errors():
+		case <-deadline:
+			require.FailNow("polling did not start")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	// the polled directories can not be added again
+	require.NoError(watcher.HealthCheck())
+
+	require.NoError(watcher.Remove(rootDirectory))
+	require.Equal(0, watcher.polledCount())
+
+	for _, dir := range dirs {
+		require.NoError(ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("DATA"), 0644))
+	}
+	clock.Advance(time.Second)
+	select {
+	case ev := <-events:
+		require.FailNow("polling went on after remove", ev.Name)
+	case <-time.After(time.Millisecond * 200):
+	}
+}
@@ -0,0 +1,84 @@
+package dirwatch
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WatchSetEvent is a change of the set of watched paths: Path got watched
+// if Added is set, and stopped being watched otherwise.
+type WatchSetEvent struct {
+	Path  string
+	Added bool
+}
+
+// watchSetBuffer is the buffer of the channels of WatchSetChanges.
+const watchSetBuffer = 100
+
+type watchSetSubs struct {
+	mx  sync.Mutex
+	set map[chan WatchSetEvent]struct{}
+}
+
+// WatchSetChanges returns a channel that receives the changes of the set of
+// watched paths from now on, as directories get watched and stop being
+// watched while the tree evolves, for driving a live view of it; Dump
+// gives the set at one point in time. It is buffered, and misses changes
+// once the buffer is full, so a slow reader never holds up the watcher.
+// The channel is closed when the watcher stops.
+func (dw *Watcher) WatchSetChanges() <-chan WatchSetEvent {
+	changes := make(chan WatchSetEvent, watchSetBuffer)
+	dw.watchSet.mx.Lock()
+	defer dw.watchSet.mx.Unlock()
+	select {
+	case <-dw.stopped():
+		close(changes)
+	default:
+		dw.watchSet.set[changes] = struct{}{}
+	}
+	return changes
+}
+
+func (dw *Watcher) closeWatchSet() {
+	dw.watchSet.mx.Lock()
+	defer dw.watchSet.mx.Unlock()
+	for changes := range dw.watchSet.set {
+		delete(dw.watchSet.set, changes)
+		close(changes)
+	}
+}
+
+// registerPath sets the registration of p, reporting it if p is new to the
+// watch set. It runs on the agent goroutine.
+func (dw *Watcher) registerPath(p string, wp watchedPath) {
+	_, known := dw.paths[p]
+	dw.paths[p] = wp
+	if !known {
+		atomic.AddInt64(&dw.counters.watched, 1)
+		dw.publishWatchSet(WatchSetEvent{Path: p, Added: true})
+	}
+}
+
+// unregisterPath drops p from the watch set, reporting it if p was in it.
+// It runs on the agent goroutine.
+func (dw *Watcher) unregisterPath(p string) {
+	if _, known := dw.paths[p]; !known {
+		return
+	}
+	delete(dw.paths, p)
+	dw.unpoll(p)
+	atomic.AddInt64(&dw.counters.watched, -1)
+	dw.publishWatchSet(WatchSetEvent{Path: p})
+}
+
+// publishWatchSet sends change to the readers with room for it.
+func (dw *Watcher) publishWatchSet(change WatchSetEvent) {
+	dw.watchSet.mx.Lock()
+	defer dw.watchSet.mx.Unlock()
+	for changes := range dw.watchSet.set {
+		select {
+		case changes <- change:
+		default:
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package dirwatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchSetChanges(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-watchset")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	watcher := New(Notify(func(Event) {}))
+	defer watcher.Stop()
+	changes := watcher.WatchSetChanges()
+
+	next := func() WatchSetEvent {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(time.Second * 5):
+			require.FailNow("watch set change not received")
+		}
+		return WatchSetEvent{}
+	}
+
+	watcher.Add(rootDirectory, true)
+	require.Equal(WatchSetEvent{Path: rootDirectory, Added: true}, next())
+
+	// each level gets watched once its parent is
+	outer := filepath.Join(rootDirectory, "outer")
+	require.NoError(os.Mkdir(outer, 0755))
+	require.Equal(WatchSetEvent{Path: outer, Added: true}, next())
+	inner := filepath.Join(outer, "inner")
+	require.NoError(os.Mkdir(inner, 0755))
+	require.Equal(WatchSetEvent{Path: inner, Added: true}, next())
+
+	require.NoError(os.Remove(inner))
+	require.Equal(WatchSetEvent{Path: inner}, next())
+
+	watcher.Stop()
+	for range changes {
+	}
+	_, ok := <-watcher.WatchSetChanges()
+	require.False(ok)
+}
+
+func TestWatchSetOrder(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-watchset")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+	makeTree(t, rootDirectory, 3, 3)
+
+	var expected []string
+	require.NoError(filepath.Walk(rootDirectory, func(path string, f os.FileInfo, err error) error {
+		if f.IsDir() {
+			expected = append(expected, path)
+		}
+		return err
+	}))
+
+	for i := 0; i < 10; i++ {
+		fake := newFakeBackend()
+		watcher := New(
+			Notify(func(Event) {}),
+			withBackend(func() (backend, error) { return fake, nil }))
+		changes := watcher.WatchSetChanges()
+		if i%2 == 0 {
+			require.NoError(watcher.AddContext(context.Background(), rootDirectory, true))
+		} else {
+			_, errs := watcher.AddRecursiveSync(rootDirectory)
+			require.Empty(errs)
+		}
+
+		var watched []string
+		for len(watched) < len(expected) {
+			select {
+			case change := <-changes:
+				require.True(change.Added)
+				watched = append(watched, change.Path)
+			case <-time.After(time.Second * 5):
+				require.FailNow("watch set changes not received", "%v", watched)
+			}
+		}
+		watcher.Stop()
+		// parents before children, siblings in lexical order, every time
+		require.Equal(expected, watched)
+	}
+}
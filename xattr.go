@@ -0,0 +1,36 @@
+package dirwatch
+
+// TrackXattrs reports changes of the extended attributes of files with a
+// synthetic Xattr event, following the Chmod or Write event that revealed
+// them. The attributes of a file are recorded from its first event on, and
+// compared on each Chmod or Write event after that. It is supported on Linux
+// and macOS, and does nothing elsewhere.
+func TrackXattrs() Option {
+	return func(opt *options) {
+		opt.xattrs = true
+	}
+}
+
+// trackXattrs records the extended attributes of the file of ev and emits
+// an Xattr event if they have changed. It runs on the agent goroutine.
+func (dw *Watcher) trackXattrs(ev Event, isdir bool) {
+	if !dw.xattrs || isdir {
+		return
+	}
+	if ev.Op&(Remove|Rename) != 0 {
+		dw.state.remove(stateKey{stateXattrs, ev.Name})
+		return
+	}
+	current, err := readXattrs(ev.Name)
+	if err != nil {
+		return
+	}
+	key := stateKey{stateXattrs, ev.Name}
+	last, seen := dw.state.get(key)
+	dw.state.set(key, current)
+	if !seen || last.(string) == current || ev.Op&(Chmod|Write) == 0 {
+		return
+	}
+	ev.Op = Xattr
+	dw.deliver(ev)
+}
@@ -0,0 +1,58 @@
+//go:build linux
+// +build linux
+
+package dirwatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestTrackXattrs(t *testing.T) {
+	require := require.New(t)
+
+	rootDirectory, err := ioutil.TempDir(os.TempDir(), "dirwatch-xattr")
+	require.NoError(err)
+	defer os.RemoveAll(rootDirectory)
+
+	probe := filepath.Join(rootDirectory, "probe")
+	require.NoError(ioutil.WriteFile(probe, nil, 0666))
+	if err := unix.Setxattr(probe, "user.dirwatch", []byte("1"), 0); err != nil {
+		t.Skip("extended attributes not supported:", err)
+	}
+
+	events := make(chan Event, 100)
+	watcher := New(Notify(func(ev Event) { events <- ev }), TrackXattrs())
+	defer watcher.Stop()
+
+	watcher.Add(rootDirectory, false)
+	<-time.After(time.Millisecond * 50)
+
+	name := filepath.Join(rootDirectory, "text.txt")
+	require.NoError(ioutil.WriteFile(name, []byte("DATA"), 0666))
+	<-time.After(time.Millisecond * 100)
+	require.NoError(unix.Setxattr(name, "user.label", []byte("secret"), 0))
+	<-time.After(time.Millisecond * 100)
+	require.NoError(os.Chmod(name, 0600))
+
+	var xattrs int
+T1:
+	for {
+		select {
+		case ev := <-events:
+			if ev.Op == Xattr {
+				require.Equal(name, ev.Name)
+				xattrs++
+			}
+		case <-time.After(time.Millisecond * 300):
+			break T1
+		}
+	}
+	require.Equal(1, xattrs)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package dirwatch
+
+import "github.com/pkg/errors"
+
+func readXattrs(path string) (string, error) {
+	return "", errors.New("extended attributes are not supported")
+}
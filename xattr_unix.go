@@ -0,0 +1,50 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package dirwatch
+
+import (
+	"bytes"
+	"sort"
+
+	"golang.org/x/sys/unix"
+)
+
+// readXattrs returns the extended attributes of path, encoded as a string
+// that is equal for equal sets of attributes.
+func readXattrs(path string) (string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return "", err
+	}
+	list := make([]byte, size)
+	size, err = unix.Listxattr(path, list)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, name := range bytes.Split(list[:size], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		size, err := unix.Getxattr(path, name, nil)
+		if err != nil {
+			return "", err
+		}
+		value := make([]byte, size)
+		size, err = unix.Getxattr(path, name, value)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(name)
+		buf.WriteByte(0)
+		buf.Write(value[:size])
+		buf.WriteByte(0)
+	}
+	return buf.String(), nil
+}